@@ -0,0 +1,153 @@
+package resource
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// cacheTransport makes conditional requests for anything it has fetched
+// before. GitHub answers those with 304 Not Modified when nothing has
+// changed, which does not count against the rate limit, and the cached
+// response is returned instead.
+//
+// Entries are stored on disk, keyed by the URL and a hash of the
+// Authorization header so that different credentials never share responses.
+// They are written to a temporary file and renamed into place, so several
+// processes can share the same directory.
+type cacheTransport struct {
+	base http.RoundTripper
+	dir  string
+}
+
+type cacheEntry struct {
+	ETag       string      `json:"etag"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return t.base.RoundTrip(req)
+	}
+
+	path := filepath.Join(t.dir, cacheKey(req)+".json")
+	entry := readCacheEntry(path)
+
+	if entry != nil {
+		req = req.Clone(req.Context())
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+
+	res, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusNotModified && entry != nil {
+		res.Body.Close()
+		return entry.response(req, res.Header), nil
+	}
+
+	etag := res.Header.Get("ETag")
+	if res.StatusCode != http.StatusOK || etag == "" {
+		return res, nil
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	// Failing to write the cache only costs a full request next time.
+	_ = writeCacheEntry(t.dir, path, &cacheEntry{
+		ETag:       etag,
+		StatusCode: res.StatusCode,
+		Header:     res.Header,
+		Body:       body,
+	})
+
+	return res, nil
+}
+
+// response rebuilds the cached response, keeping the rate limit headers from
+// the 304 so that they stay current.
+func (e *cacheEntry) response(req *http.Request, notModified http.Header) *http.Response {
+	header := http.Header{}
+	for name, values := range e.Header {
+		header[name] = values
+	}
+	for name, values := range notModified {
+		if strings.HasPrefix(name, "X-Ratelimit-") {
+			header[name] = values
+		}
+	}
+
+	return &http.Response{
+		Status:        http.StatusText(e.StatusCode),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func cacheKey(req *http.Request) string {
+	token := sha256.Sum256([]byte(req.Header.Get("Authorization")))
+	key := sha256.Sum256([]byte(req.URL.String() + "\n" + hex.EncodeToString(token[:])))
+	return hex.EncodeToString(key[:])
+}
+
+func readCacheEntry(path string) *cacheEntry {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var entry cacheEntry
+	if err := json.Unmarshal(contents, &entry); err != nil || entry.ETag == "" {
+		return nil
+	}
+
+	return &entry
+}
+
+func writeCacheEntry(dir, path string, entry *cacheEntry) error {
+	contents, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".entry-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,142 @@
+package resource_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Response cache", func() {
+	var (
+		server   *httptest.Server
+		source   resource.Source
+		cacheDir string
+
+		lock              sync.Mutex
+		etag              string
+		body              string
+		ifNoneMatch       []string
+		notModifiedCount  int
+		remainingRequests int
+	)
+
+	BeforeEach(func() {
+		var err error
+		cacheDir, err = ioutil.TempDir("", "github-deployment-cache")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		etag = `"v1"`
+		body = `[{"id":1}]`
+		ifNoneMatch = []string{}
+		notModifiedCount = 0
+		remainingRequests = 5000
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			ifNoneMatch = append(ifNoneMatch, r.Header.Get("If-None-Match"))
+			w.Header().Set("X-RateLimit-Remaining", fmt.Sprint(remainingRequests))
+
+			if r.Header.Get("If-None-Match") == etag {
+				notModifiedCount++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+
+			remainingRequests--
+			w.Header().Set("ETag", etag)
+			fmt.Fprint(w, body)
+		}))
+
+		source = resource.Source{
+			User:         "owner",
+			Repository:   "repo",
+			AccessToken:  "token-one",
+			GitHubAPIURL: server.URL + "/",
+			CacheDir:     cacheDir,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+		Ω(os.RemoveAll(cacheDir)).Should(Succeed())
+	})
+
+	listDeploymentIDs := func() []int64 {
+		client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		deployments, err := client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ids := []int64{}
+		for _, deployment := range deployments {
+			ids = append(ids, deployment.GetID())
+		}
+		return ids
+	}
+
+	It("serves unchanged responses from the cache across clients", func() {
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+
+		Ω(ifNoneMatch).Should(Equal([]string{"", `"v1"`}))
+		Ω(notModifiedCount).Should(Equal(1))
+	})
+
+	It("fetches and caches the new response when it changes", func() {
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+
+		etag = `"v2"`
+		body = `[{"id":2},{"id":1}]`
+		Ω(listDeploymentIDs()).Should(Equal([]int64{2, 1}))
+		Ω(listDeploymentIDs()).Should(Equal([]int64{2, 1}))
+
+		Ω(ifNoneMatch).Should(Equal([]string{"", `"v1"`, `"v2"`}))
+	})
+
+	It("does not share cached responses between tokens", func() {
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+
+		source.AccessToken = "token-two"
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+
+		Ω(ifNoneMatch).Should(Equal([]string{"", ""}))
+	})
+
+	It("is safe to use from several clients at once", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+			}()
+		}
+		wg.Wait()
+
+		entries, err := ioutil.ReadDir(cacheDir)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(entries).Should(HaveLen(1))
+	})
+
+	It("does not cache anything unless a cache_dir is set", func() {
+		source.CacheDir = ""
+
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+		Ω(listDeploymentIDs()).Should(Equal([]int64{1}))
+
+		Ω(ifNoneMatch).Should(Equal([]string{"", ""}))
+	})
+})
@@ -1,12 +1,26 @@
 package resource
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"sort"
 	"strconv"
 )
 
+const defaultConcurrency = 8
+
+// Version modes control which deployments check emits as versions.
+const (
+	// VersionModeLatest only emits the newest deployment.
+	VersionModeLatest = "latest"
+	// VersionModeEvery emits every deployment since the current version.
+	VersionModeEvery = "every"
+	// VersionModeLatestPerEnvironment emits the newest deployment to each
+	// environment since the current version.
+	VersionModeLatestPerEnvironment = "latest_per_environment"
+)
+
 type CheckCommand struct {
 	github GitHub
 	writer io.Writer
@@ -19,54 +33,226 @@ func NewCheckCommand(github GitHub, writer io.Writer) *CheckCommand {
 	}
 }
 
-func (c *CheckCommand) Run(request CheckRequest) ([]Version, error) {
-	fmt.Fprintln(c.writer, "getting deployments list")
-	deployments, err := c.github.ListDeployments()
+func (c *CheckCommand) Run(ctx context.Context, request CheckRequest) ([]Version, error) {
+	mode := request.Source.VersionMode
+	if mode == "" {
+		mode = VersionModeEvery
+	}
+	if !validVersionMode(mode) {
+		return []Version{}, fmt.Errorf("unknown version_mode %q, must be one of %s, %s or %s",
+			mode, VersionModeLatest, VersionModeEvery, VersionModeLatestPerEnvironment)
+	}
+
+	filters, err := newDeploymentFilters(request.Source)
+	if err != nil {
+		return []Version{}, err
+	}
+
+	// A missing or unparseable version leaves MinID at zero, which pages
+	// through every deployment.
+	minID, _ := strconv.ParseInt(request.Version.ID, 10, 64)
 
+	deployments, err := c.listDeployments(ctx, request.Source, minID)
 	if err != nil {
 		return []Version{}, err
 	}
 
-	var latestVersions []Version
+	var candidates []*Deployment
 
 	for _, deployment := range deployments {
-		if len(request.Source.Environments) > 0 {
-			found := false
-			for _, env := range request.Source.Environments {
-				if env == *deployment.Environment {
-					found = true
-				}
-			}
+		if !filters.matches(deployment) {
+			continue
+		}
 
-			if !found {
-				continue
-			}
+		// The latest mode always considers every deployment it fetched, so
+		// that it can fall back to an older one if the current version has
+		// been deleted.
+		if mode != VersionModeLatest && request.Version.ID != "" && deployment.GetID() < minID {
+			continue
 		}
 
-		id := *deployment.ID
-		lastID, err := strconv.ParseInt(request.Version.ID, 10, 64)
-		if err != nil || id >= lastID {
-			latestVersions = append(latestVersions, Version{ID: strconv.FormatInt(id, 10)})
+		candidates = append(candidates, deployment)
+	}
+
+	latestStates := map[int64]string{}
+
+	if len(request.Source.StatusFilter) > 0 {
+		err = fetchLatestStates(ctx, c.github, c.writer, deploymentIDs(candidates), latestStates, request.Source.Concurrency)
+		if err != nil {
+			return []Version{}, err
 		}
+
+		candidates = filterByState(candidates, latestStates, request.Source.StatusFilter)
 	}
 
-	if len(latestVersions) == 0 {
+	if len(candidates) == 0 {
 		return []Version{}, nil
 	}
 
-	sort.Slice(latestVersions[:], func(i, j int) bool {
-		iID, _ := strconv.Atoi(latestVersions[i].ID)
-		jID, _ := strconv.Atoi(latestVersions[j].ID)
-		return iID < jID
+	sort.Slice(candidates, func(i, j int) bool {
+		return candidates[i].GetID() < candidates[j].GetID()
 	})
 
-	latestVersion := latestVersions[len(latestVersions)-1]
+	switch mode {
+	case VersionModeLatest:
+		candidates = candidates[len(candidates)-1:]
+	case VersionModeLatestPerEnvironment:
+		candidates = latestPerEnvironment(candidates)
+	default:
+		if request.Version.ID == "" {
+			candidates = candidates[len(candidates)-1:]
+		}
+	}
 
-	if request.Version.ID == "" {
-		return []Version{
-			latestVersion,
-		}, nil
+	newIDs := deploymentIDs(candidates)
+
+	// Recording the latest status in each version means that a change of
+	// status is seen as a new version. Deployments older than the current
+	// version are not listed, so changes to their statuses are not seen.
+	if request.Source.TrackStatuses {
+		err = fetchLatestStates(ctx, c.github, c.writer, newIDs, latestStates, request.Source.Concurrency)
+		if err != nil {
+			return []Version{}, err
+		}
+	}
+
+	latestVersions := []Version{}
+	for _, id := range newIDs {
+		version := Version{ID: strconv.FormatInt(id, 10)}
+		if request.Source.TrackStatuses {
+			version.Statuses = latestStates[id]
+		}
+		latestVersions = append(latestVersions, version)
 	}
 
 	return latestVersions, nil
 }
+
+// listDeployments lets GitHub filter deployments by environment, running one
+// query per environment and merging the results.
+func (c *CheckCommand) listDeployments(ctx context.Context, source Source, minID int64) ([]*Deployment, error) {
+	if len(source.Environments) == 0 {
+		fmt.Fprintln(c.writer, "getting deployments list")
+		return c.github.ListDeployments(ctx, ListDeploymentsOptions{MinID: minID})
+	}
+
+	seen := map[int64]bool{}
+	merged := []*Deployment{}
+
+	for _, env := range source.Environments {
+		fmt.Fprintf(c.writer, "getting deployments list for environment %s\n", env)
+		deployments, err := c.github.ListDeployments(ctx, ListDeploymentsOptions{
+			Environment: env,
+			MinID:       minID,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, deployment := range deployments {
+			if seen[deployment.GetID()] {
+				continue
+			}
+			seen[deployment.GetID()] = true
+			merged = append(merged, deployment)
+		}
+	}
+
+	return merged, nil
+}
+
+// fetchLatestStates looks up the latest status of each deployment that is
+// not already in states, using a bounded number of concurrent requests. A
+// deployment without any statuses is recorded with an empty state.
+func fetchLatestStates(ctx context.Context, github GitHub, writer io.Writer, ids []int64, states map[int64]string, concurrency int) error {
+	missing := []int64{}
+	for _, id := range ids {
+		if _, ok := states[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	fmt.Fprintf(writer, "getting latest statuses for %d deployments\n", len(missing))
+
+	fetched := make([]string, len(missing))
+	err := inParallel(len(missing), concurrency, func(i int) error {
+		status, err := github.GetLatestDeploymentStatus(ctx, missing[i])
+		if err != nil {
+			return err
+		}
+
+		fetched[i] = status.GetState()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, id := range missing {
+		states[id] = fetched[i]
+	}
+
+	return nil
+}
+
+// filterByState keeps the deployments whose latest status is one of the
+// wanted states. GitHub shows deployments without any statuses as pending, so
+// they are treated as pending here too.
+func filterByState(deployments []*Deployment, states map[int64]string, wanted []string) []*Deployment {
+	filtered := []*Deployment{}
+	for _, deployment := range deployments {
+		state := states[deployment.GetID()]
+		if state == "" {
+			state = "pending"
+		}
+
+		for _, w := range wanted {
+			if w == state {
+				filtered = append(filtered, deployment)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// latestPerEnvironment keeps the newest of the sorted deployments for each
+// environment, preserving their order.
+func latestPerEnvironment(deployments []*Deployment) []*Deployment {
+	newest := map[string]int64{}
+	for _, deployment := range deployments {
+		newest[deployment.GetEnvironment()] = deployment.GetID()
+	}
+
+	latest := []*Deployment{}
+	for _, deployment := range deployments {
+		if newest[deployment.GetEnvironment()] == deployment.GetID() {
+			latest = append(latest, deployment)
+		}
+	}
+	return latest
+}
+
+func deploymentIDs(deployments []*Deployment) []int64 {
+	ids := []int64{}
+	for _, deployment := range deployments {
+		ids = append(ids, deployment.GetID())
+	}
+	return ids
+}
+
+func validVersionMode(mode string) bool {
+	switch mode {
+	case VersionModeLatest, VersionModeEvery, VersionModeLatestPerEnvironment:
+		return true
+	}
+	return false
+}
@@ -1,7 +1,11 @@
 package resource_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -17,7 +21,7 @@ var _ = Describe("Check Command", func() {
 		command      *resource.CheckCommand
 		githubClient *fakes.FakeGitHub
 
-		returnedDeployments        []*github.Deployment
+		returnedDeployments        []*resource.Deployment
 		returnedDeploymentStatuses []*github.DeploymentStatus
 
 		requestedEnvironments []string
@@ -28,23 +32,43 @@ var _ = Describe("Check Command", func() {
 		githubClient = &fakes.FakeGitHub{}
 		command = resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-		returnedDeployments = []*github.Deployment{}
+		returnedDeployments = []*resource.Deployment{}
 		returnedDeploymentStatuses = []*github.DeploymentStatus{}
 	})
 
 	JustBeforeEach(func() {
-		githubClient.ListDeploymentsReturns(returnedDeployments, nil)
+		// Filter on environment the same way the GitHub API does.
+		githubClient.ListDeploymentsStub = func(ctx context.Context, opts resource.ListDeploymentsOptions) ([]*resource.Deployment, error) {
+			deployments := []*resource.Deployment{}
+			for _, deployment := range returnedDeployments {
+				if opts.Environment == "" || opts.Environment == deployment.GetEnvironment() {
+					deployments = append(deployments, deployment)
+				}
+			}
+			return deployments, nil
+		}
 		githubClient.ListDeploymentStatusesReturns(returnedDeploymentStatuses, nil)
 	})
 
+	// run checks from the first deployment, so that every later deployment
+	// that matches the source is emitted.
+	run := func(source resource.Source) []resource.Version {
+		versions, err := command.Run(context.Background(), resource.CheckRequest{
+			Source:  source,
+			Version: resource.Version{ID: "1"},
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		return versions
+	}
+
 	Context("when this is the first time that the resource has been run", func() {
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
-				returnedDeployments = []*github.Deployment{}
+				returnedDeployments = []*resource.Deployment{}
 			})
 
 			It("returns no versions", func() {
-				versions, err := command.Run(resource.CheckRequest{})
+				versions, err := command.Run(context.Background(), resource.CheckRequest{})
 				Ω(err).ShouldNot(HaveOccurred())
 				Ω(versions).Should(BeEmpty())
 			})
@@ -52,7 +76,7 @@ var _ = Describe("Check Command", func() {
 
 		Context("when there are deployments", func() {
 			BeforeEach(func() {
-				returnedDeployments = []*github.Deployment{
+				returnedDeployments = []*resource.Deployment{
 					newDeployment(3),
 					newDeployment(2),
 					newDeployment(1),
@@ -60,7 +84,7 @@ var _ = Describe("Check Command", func() {
 			})
 
 			It("outputs the most recent version if there is no current version", func() {
-				versions, err := command.Run(resource.CheckRequest{})
+				versions, err := command.Run(context.Background(), resource.CheckRequest{})
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(versions).Should(HaveLen(1))
@@ -74,11 +98,11 @@ var _ = Describe("Check Command", func() {
 	Context("when there is a current version", func() {
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
-				returnedDeployments = []*github.Deployment{}
+				returnedDeployments = []*resource.Deployment{}
 			})
 
 			It("returns no versions", func() {
-				versions, err := command.Run(resource.CheckRequest{
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
 					Version: resource.Version{
 						ID: "3",
 					},
@@ -90,7 +114,7 @@ var _ = Describe("Check Command", func() {
 
 		Context("when there are deployments", func() {
 			BeforeEach(func() {
-				returnedDeployments = []*github.Deployment{
+				returnedDeployments = []*resource.Deployment{
 					newDeployment(3),
 					newDeployment(2),
 					newDeployment(1),
@@ -100,7 +124,7 @@ var _ = Describe("Check Command", func() {
 			It("outputs the most recent version if it matches the current version", func() {
 				command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-				versions, err := command.Run(resource.CheckRequest{
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
 					Version: resource.Version{
 						ID: "3",
 					},
@@ -112,10 +136,25 @@ var _ = Describe("Check Command", func() {
 				}))
 			})
 
+			It("only pages back as far as the current version", func() {
+				_, err := command.Run(context.Background(), resource.CheckRequest{
+					Version: resource.Version{
+						ID: "2",
+					},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(githubClient.ListDeploymentsCallCount()).Should(Equal(1))
+				_, opts := githubClient.ListDeploymentsArgsForCall(0)
+				Ω(opts).Should(Equal(resource.ListDeploymentsOptions{
+					MinID: 2,
+				}))
+			})
+
 			It("outputs versions later than and including the current", func() {
 				command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-				versions, err := command.Run(resource.CheckRequest{
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
 					Version: resource.Version{
 						ID: "2",
 					},
@@ -139,11 +178,11 @@ var _ = Describe("Check Command", func() {
 		Context("when there are no deployments", func() {
 			BeforeEach(func() {
 				requestedEnvironments = []string{"prd", "production"}
-				returnedDeployments = []*github.Deployment{}
+				returnedDeployments = []*resource.Deployment{}
 			})
 
 			It("returns no versions", func() {
-				versions, err := command.Run(resource.CheckRequest{
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
 					Source: resource.Source{
 						Environments: requestedEnvironments,
 					},
@@ -160,7 +199,7 @@ var _ = Describe("Check Command", func() {
 			BeforeEach(func() {
 				requestedEnvironments = []string{"production"}
 				unwantedEnvironment = "dev"
-				returnedDeployments = []*github.Deployment{
+				returnedDeployments = []*resource.Deployment{
 					newDeploymentWithEnvironment(3, unwantedEnvironment),
 					newDeploymentWithEnvironment(2, unwantedEnvironment),
 					newDeploymentWithEnvironment(1, unwantedEnvironment),
@@ -170,7 +209,7 @@ var _ = Describe("Check Command", func() {
 			It("returns no versions", func() {
 				command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-				versions, err := command.Run(resource.CheckRequest{
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
 					Source: resource.Source{
 						Environments: requestedEnvironments,
 					},
@@ -184,7 +223,7 @@ var _ = Describe("Check Command", func() {
 			BeforeEach(func() {
 				requestedEnvironments = []string{"production", "prd"}
 				unwantedEnvironment = "dev"
-				returnedDeployments = []*github.Deployment{
+				returnedDeployments = []*resource.Deployment{
 					newDeploymentWithEnvironment(15, unwantedEnvironment),
 					newDeploymentWithEnvironment(10, requestedEnvironments[0]),
 					newDeploymentWithEnvironment(3, requestedEnvironments[1]),
@@ -193,12 +232,44 @@ var _ = Describe("Check Command", func() {
 				}
 			})
 
+			It("asks GitHub for each environment separately", func() {
+				_, err := command.Run(context.Background(), resource.CheckRequest{
+					Source: resource.Source{
+						Environments: requestedEnvironments,
+					},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(githubClient.ListDeploymentsCallCount()).Should(Equal(2))
+				_, opts := githubClient.ListDeploymentsArgsForCall(0)
+				Ω(opts.Environment).Should(Equal("production"))
+				_, opts = githubClient.ListDeploymentsArgsForCall(1)
+				Ω(opts.Environment).Should(Equal("prd"))
+			})
+
+			It("does not repeat deployments returned for more than one environment", func() {
+				versions, err := command.Run(context.Background(), resource.CheckRequest{
+					Source: resource.Source{
+						Environments: []string{"production", "production"},
+					},
+					Version: resource.Version{
+						ID: "2",
+					},
+				})
+				Ω(err).ShouldNot(HaveOccurred())
+
+				Ω(versions).Should(Equal([]resource.Version{
+					{ID: "2"},
+					{ID: "10"},
+				}))
+			})
+
 			Context("when this is the first time that the resource has been run", func() {
 
 				It("outputs the most recent version related to filtered environment if there is no current version", func() {
 					command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-					versions, err := command.Run(resource.CheckRequest{
+					versions, err := command.Run(context.Background(), resource.CheckRequest{
 						Source: resource.Source{
 							Environments: requestedEnvironments,
 						},
@@ -218,7 +289,7 @@ var _ = Describe("Check Command", func() {
 				It("outputs the most recent version related to the filtered environment if it matches the current version", func() {
 					command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-					versions, err := command.Run(resource.CheckRequest{
+					versions, err := command.Run(context.Background(), resource.CheckRequest{
 						Source: resource.Source{
 							Environments: requestedEnvironments,
 						},
@@ -238,7 +309,7 @@ var _ = Describe("Check Command", func() {
 				It("outputs versions later than and including the current", func() {
 					command := resource.NewCheckCommand(githubClient, ioutil.Discard)
 
-					versions, err := command.Run(resource.CheckRequest{
+					versions, err := command.Run(context.Background(), resource.CheckRequest{
 						Source: resource.Source{
 							Environments: requestedEnvironments,
 						},
@@ -262,4 +333,465 @@ var _ = Describe("Check Command", func() {
 		})
 
 	})
+	Context("when tracking deployment statuses", func() {
+		var latestStatuses map[int64]string
+
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeployment(3),
+				newDeployment(2),
+				newDeployment(1),
+			}
+
+			latestStatuses = map[int64]string{
+				3: "pending",
+				2: "success",
+			}
+		})
+
+		JustBeforeEach(func() {
+			githubClient.GetLatestDeploymentStatusStub = func(ctx context.Context, ID int64) (*github.DeploymentStatus, error) {
+				state, ok := latestStatuses[ID]
+				if !ok {
+					return nil, nil
+				}
+				return &github.DeploymentStatus{State: github.String(state)}, nil
+			}
+		})
+
+		It("includes the latest status in the most recent version", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					TrackStatuses: true,
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "3", Statuses: "pending"},
+			}))
+			Ω(githubClient.GetLatestDeploymentStatusCallCount()).Should(Equal(1))
+			Ω(githubClient.ListDeploymentStatusesCallCount()).Should(Equal(0))
+		})
+
+		It("includes the latest status in every new version", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					TrackStatuses: true,
+				},
+				Version: resource.Version{
+					ID:       "1",
+					Statuses: "",
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "1", Statuses: ""},
+				{ID: "2", Statuses: "success"},
+				{ID: "3", Statuses: "pending"},
+			}))
+		})
+
+		It("emits a new version when the status of the current deployment changes", func() {
+			latestStatuses[3] = "success"
+
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					TrackStatuses: true,
+				},
+				Version: resource.Version{
+					ID:       "3",
+					Statuses: "pending",
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "3", Statuses: "success"},
+			}))
+		})
+
+		It("does not see status changes of deployments older than the current version", func() {
+			latestStatuses[2] = "failure"
+
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					TrackStatuses: true,
+				},
+				Version: resource.Version{
+					ID:       "3",
+					Statuses: "pending",
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "3", Statuses: "pending"},
+			}))
+			_, ID := githubClient.GetLatestDeploymentStatusArgsForCall(0)
+			Ω(githubClient.GetLatestDeploymentStatusCallCount()).Should(Equal(1))
+			Ω(ID).Should(Equal(int64(3)))
+		})
+
+		It("does not fetch statuses unless asked to", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "3"},
+			}))
+			Ω(githubClient.GetLatestDeploymentStatusCallCount()).Should(Equal(0))
+		})
+	})
+
+	Context("when filtering on the latest deployment status", func() {
+		var (
+			latestStatuses map[int64]string
+
+			lock          sync.Mutex
+			inFlight      int
+			maxInFlight   int
+			statusesDelay time.Duration
+		)
+
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeployment(5),
+				newDeployment(4),
+				newDeployment(3),
+				newDeployment(2),
+				newDeployment(1),
+			}
+
+			latestStatuses = map[int64]string{
+				5: "success",
+				4: "in_progress",
+				3: "queued",
+				2: "pending",
+			}
+
+			inFlight = 0
+			maxInFlight = 0
+			statusesDelay = 0
+		})
+
+		JustBeforeEach(func() {
+			githubClient.GetLatestDeploymentStatusStub = func(ctx context.Context, ID int64) (*github.DeploymentStatus, error) {
+				lock.Lock()
+				inFlight++
+				if inFlight > maxInFlight {
+					maxInFlight = inFlight
+				}
+				lock.Unlock()
+
+				time.Sleep(statusesDelay)
+
+				lock.Lock()
+				inFlight--
+				lock.Unlock()
+
+				state, ok := latestStatuses[ID]
+				if !ok {
+					return nil, nil
+				}
+				return &github.DeploymentStatus{State: github.String(state)}, nil
+			}
+		})
+
+		It("only emits deployments with a matching latest status", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					StatusFilter: []string{"queued", "in_progress"},
+				},
+				Version: resource.Version{
+					ID: "1",
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "3"},
+				{ID: "4"},
+			}))
+		})
+
+		It("outputs the most recent matching version if there is no current version", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					StatusFilter: []string{"queued", "in_progress"},
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "4"},
+			}))
+		})
+
+		It("treats deployments without statuses as pending", func() {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					StatusFilter: []string{"pending"},
+				},
+				Version: resource.Version{
+					ID: "1",
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(versions).Should(Equal([]resource.Version{
+				{ID: "1"},
+				{ID: "2"},
+			}))
+		})
+
+		It("fetches statuses concurrently up to the configured limit", func() {
+			statusesDelay = 20 * time.Millisecond
+
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					StatusFilter: []string{"pending"},
+					Concurrency:  2,
+				},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.GetLatestDeploymentStatusCallCount()).Should(Equal(5))
+			Ω(maxInFlight).Should(Equal(2))
+		})
+
+		It("returns an error if fetching a status fails", func() {
+			githubClient.GetLatestDeploymentStatusReturns(nil, errors.New("disaster"))
+
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					StatusFilter: []string{"pending"},
+				},
+			})
+			Ω(err).Should(MatchError("disaster"))
+		})
+	})
+
+	Context("when filtering with patterns", func() {
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeployment(6, withEnvironment("production"), withRef("master"), withTask("deploy"), withCreator("alice")),
+				newDeployment(5, withEnvironment("review-12"), withRef("feature/two"), withTask("deploy"), withCreator("dependabot[bot]")),
+				newDeployment(4, withEnvironment("review-11"), withRef("feature/one"), withTask("deploy:migrations"), withCreator("bob")),
+				newDeployment(3, withEnvironment("review-10"), withRef("feature/one"), withTask("deploy"), withCreator("alice")),
+				newDeployment(2, withEnvironment("staging"), withRef("master"), withTask("deploy:migrations"), withCreator("alice")),
+				newDeployment(1, withEnvironment("review-9"), withRef("feature/one"), withTask("deploy"), withCreator("renovate[bot]")),
+			}
+		})
+
+		It("includes environments matching a glob", func() {
+			Ω(run(resource.Source{
+				EnvironmentFilter: &resource.Filter{Include: []string{"review-*"}},
+			})).Should(Equal([]resource.Version{{ID: "1"}, {ID: "3"}, {ID: "4"}, {ID: "5"}}))
+		})
+
+		It("excludes creators matching a regular expression", func() {
+			Ω(run(resource.Source{
+				CreatorFilter: &resource.Filter{Exclude: []string{`/\[bot\]$/`}},
+			})).Should(Equal([]resource.Version{{ID: "2"}, {ID: "3"}, {ID: "4"}, {ID: "6"}}))
+		})
+
+		It("matches a task exactly when the pattern has no wildcards", func() {
+			Ω(run(resource.Source{
+				TaskFilter: &resource.Filter{Include: []string{"deploy:migrations"}},
+			})).Should(Equal([]resource.Version{{ID: "2"}, {ID: "4"}}))
+		})
+
+		It("lets globs match across slashes in refs", func() {
+			Ω(run(resource.Source{
+				RefFilter: &resource.Filter{Include: []string{"feature/*"}, Exclude: []string{"*/one"}},
+			})).Should(Equal([]resource.Version{{ID: "5"}}))
+		})
+
+		It("requires every filter to match", func() {
+			Ω(run(resource.Source{
+				EnvironmentFilter: &resource.Filter{Include: []string{"review-*"}},
+				CreatorFilter:     &resource.Filter{Exclude: []string{"*[bot]"}},
+				TaskFilter:        &resource.Filter{Include: []string{"deploy"}},
+			})).Should(Equal([]resource.Version{{ID: "3"}}))
+		})
+
+		It("rejects invalid patterns before calling GitHub", func() {
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					RefFilter: &resource.Filter{Include: []string{"/feature/(/"}},
+				},
+			})
+			Ω(err).Should(MatchError(ContainSubstring(`invalid ref_filter include pattern "/feature/(/"`)))
+			Ω(githubClient.ListDeploymentsCallCount()).Should(Equal(0))
+		})
+	})
+
+	Context("when filtering on the environment flags", func() {
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeployment(4, withEnvironmentFlags(github.Bool(false), github.Bool(true))),
+				newDeployment(3, withEnvironmentFlags(github.Bool(true), github.Bool(false))),
+				newDeployment(2, withEnvironmentFlags(nil, nil)),
+				newDeployment(1, withEnvironmentFlags(github.Bool(true), nil)),
+			}
+		})
+
+		It("only includes production environments with production_only", func() {
+			Ω(run(resource.Source{ProductionOnly: true})).Should(Equal([]resource.Version{{ID: "4"}}))
+		})
+
+		It("excludes transient environments with exclude_transient", func() {
+			Ω(run(resource.Source{ExcludeTransient: true})).Should(Equal([]resource.Version{{ID: "2"}, {ID: "4"}}))
+		})
+	})
+
+	Context("when filtering on the payload", func() {
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeployment(5, withPayload(`{"concourse_payload":{"build_pipeline_name":"api","build_team_name":"main"},"app":"api","replicas":3}`)),
+				newDeployment(4, withPayload(`{"concourse_payload":{"build_pipeline_name":"web","build_team_name":"main"},"app":"web"}`)),
+				newDeployment(3, withPayload(`"{\"concourse_payload\":{\"build_pipeline_name\":\"api\",\"build_team_name\":\"main\"},\"app\":\"api-worker\"}"`)),
+				newDeployment(2, withPayload(`{"concourse_payload":{"build_pipeline_name":"api","build_team_name":"other"}}`)),
+				newDeployment(1),
+			}
+		})
+
+		It("matches values that are equal", func() {
+			Ω(run(resource.Source{
+				PayloadFilter: []resource.PayloadFilter{
+					{Path: "concourse_payload.build_pipeline_name", Equals: "api"},
+					{Path: ".replicas", Equals: float64(3)},
+				},
+			})).Should(Equal([]resource.Version{{ID: "5"}}))
+		})
+
+		It("matches values against a regular expression", func() {
+			Ω(run(resource.Source{
+				PayloadFilter: []resource.PayloadFilter{
+					{Path: "app", Matches: "^api"},
+				},
+			})).Should(Equal([]resource.Version{{ID: "3"}, {ID: "5"}}))
+		})
+
+		It("matches values that exist or not", func() {
+			Ω(run(resource.Source{
+				PayloadFilter: []resource.PayloadFilter{
+					{Path: "app", Exists: github.Bool(false)},
+				},
+			})).Should(Equal([]resource.Version{{ID: "1"}, {ID: "2"}}))
+		})
+
+		It("only matches deployments from its own pipeline", func() {
+			Ω(run(resource.Source{
+				OnlyOwnPipeline: true,
+				PipelineName:    "api",
+				TeamName:        "main",
+			})).Should(Equal([]resource.Version{{ID: "3"}, {ID: "5"}}))
+		})
+
+		It("requires the pipeline to be known when only matching its own pipeline", func() {
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					OnlyOwnPipeline: true,
+				},
+			})
+			Ω(err).Should(MatchError("only_own_pipeline requires pipeline_name and team_name to be set in the source"))
+			Ω(githubClient.ListDeploymentsCallCount()).Should(Equal(0))
+		})
+
+		It("rejects filters without exactly one condition", func() {
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					PayloadFilter: []resource.PayloadFilter{
+						{Path: "app", Equals: "api", Matches: "^api"},
+					},
+				},
+			})
+			Ω(err).Should(MatchError(`payload_filter for "app" must set exactly one of equals, matches or exists`))
+		})
+
+		It("rejects invalid patterns", func() {
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{
+					PayloadFilter: []resource.PayloadFilter{
+						{Path: "app", Matches: "(api"},
+					},
+				},
+			})
+			Ω(err).Should(MatchError(ContainSubstring(`invalid payload_filter pattern "(api" for "app"`)))
+		})
+	})
+
+	Context("when choosing a version mode", func() {
+		BeforeEach(func() {
+			returnedDeployments = []*resource.Deployment{
+				newDeploymentWithEnvironment(9, "staging"),
+				newDeploymentWithEnvironment(8, "production"),
+				newDeploymentWithEnvironment(7, "staging"),
+				newDeploymentWithEnvironment(6, "review"),
+				newDeploymentWithEnvironment(4, "production"),
+			}
+		})
+
+		runWithMode := func(mode, current string) []resource.Version {
+			versions, err := command.Run(context.Background(), resource.CheckRequest{
+				Source:  resource.Source{VersionMode: mode},
+				Version: resource.Version{ID: current},
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			return versions
+		}
+
+		Context("latest", func() {
+			It("outputs only the newest deployment", func() {
+				Ω(runWithMode("latest", "")).Should(Equal([]resource.Version{{ID: "9"}}))
+				Ω(runWithMode("latest", "7")).Should(Equal([]resource.Version{{ID: "9"}}))
+			})
+
+			It("falls back to the newest remaining deployment when the current version was deleted", func() {
+				Ω(runWithMode("latest", "10")).Should(Equal([]resource.Version{{ID: "9"}}))
+			})
+		})
+
+		Context("every", func() {
+			It("is the default", func() {
+				Ω(runWithMode("", "7")).Should(Equal(runWithMode("every", "7")))
+			})
+
+			It("outputs the newest deployment if there is no current version", func() {
+				Ω(runWithMode("every", "")).Should(Equal([]resource.Version{{ID: "9"}}))
+			})
+
+			It("outputs every deployment since the current version", func() {
+				Ω(runWithMode("every", "7")).Should(Equal([]resource.Version{{ID: "7"}, {ID: "8"}, {ID: "9"}}))
+			})
+
+			It("carries on from the current version when it was deleted", func() {
+				Ω(runWithMode("every", "5")).Should(Equal([]resource.Version{{ID: "6"}, {ID: "7"}, {ID: "8"}, {ID: "9"}}))
+			})
+		})
+
+		Context("latest_per_environment", func() {
+			It("outputs the newest deployment to each environment if there is no current version", func() {
+				Ω(runWithMode("latest_per_environment", "")).Should(Equal([]resource.Version{{ID: "6"}, {ID: "8"}, {ID: "9"}}))
+			})
+
+			It("outputs the newest deployment to each environment since the current version", func() {
+				Ω(runWithMode("latest_per_environment", "7")).Should(Equal([]resource.Version{{ID: "8"}, {ID: "9"}}))
+			})
+
+			It("carries on from the current version when it was deleted", func() {
+				Ω(runWithMode("latest_per_environment", "5")).Should(Equal([]resource.Version{{ID: "6"}, {ID: "8"}, {ID: "9"}}))
+			})
+		})
+
+		It("rejects unknown modes", func() {
+			_, err := command.Run(context.Background(), resource.CheckRequest{
+				Source: resource.Source{VersionMode: "newest"},
+			})
+			Ω(err).Should(MatchError(`unknown version_mode "newest", must be one of latest, every or latest_per_environment`))
+		})
+	})
 })
@@ -0,0 +1,171 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+)
+
+type CleanupOutCommand struct {
+	github GitHub
+	writer io.Writer
+}
+
+func NewCleanupOutCommand(github GitHub, writer io.Writer) *CleanupOutCommand {
+	return &CleanupOutCommand{
+		github: github,
+		writer: writer,
+	}
+}
+
+func (c *CleanupOutCommand) Run(ctx context.Context, sourceDir string, request OutRequest) (OutResponse, error) {
+	params := request.Params
+	if params.KeepLast == nil && params.OlderThan == nil {
+		return OutResponse{}, errors.New("one of keep_last or older_than is required")
+	}
+	if params.KeepLast != nil && *params.KeepLast < 0 {
+		return OutResponse{}, errors.New("keep_last must not be negative")
+	}
+
+	opts := ListDeploymentsOptions{}
+	if params.Environment != nil {
+		opts.Environment = *params.Environment
+	}
+
+	fmt.Fprintln(c.writer, "getting deployments list")
+	deployments, err := c.github.ListDeployments(ctx, opts)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	kept, expired := partitionDeployments(deployments, params)
+
+	// Deployments that are already inactive only need any work done when they
+	// are being deleted.
+	states := map[int64]string{}
+	err = fetchLatestStates(ctx, c.github, c.writer, deploymentIDs(expired), states, request.Source.Concurrency)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	var inactivate, remove []int64
+	for _, deployment := range expired {
+		id := deployment.GetID()
+		if states[id] != "inactive" {
+			inactivate = append(inactivate, id)
+		}
+		if params.Delete {
+			remove = append(remove, id)
+		}
+	}
+
+	prefix := ""
+	if params.DryRun {
+		prefix = "dry run: "
+	}
+	for _, id := range inactivate {
+		fmt.Fprintf(c.writer, "%smarking deployment %d inactive\n", prefix, id)
+	}
+	for _, id := range remove {
+		fmt.Fprintf(c.writer, "%sdeleting deployment %d\n", prefix, id)
+	}
+
+	if !params.DryRun {
+		err = c.cleanup(ctx, inactivate, remove, request.Source.Concurrency)
+		if err != nil {
+			return OutResponse{}, err
+		}
+	}
+
+	version := Version{Cleanup: "true"}
+	if len(deployments) > 0 {
+		version.ID = strconv.FormatInt(deployments[0].GetID(), 10)
+	}
+
+	return OutResponse{
+		Version: version,
+		Metadata: []MetadataPair{
+			{Name: "dry_run", Value: strconv.FormatBool(params.DryRun)},
+			{Name: "kept", Value: strconv.Itoa(len(kept))},
+			{Name: "inactivated", Value: joinIDs(inactivate)},
+			{Name: "deleted", Value: joinIDs(remove)},
+		},
+	}, nil
+}
+
+// partitionDeployments sorts the deployments newest first and splits them into those
+// that are kept and those that have expired. A deployment expires once there
+// are keep_last newer deployments to its environment and it is older than
+// older_than, when either is set. Deployments to environments that are not
+// transient are always kept when transient_only is set.
+func partitionDeployments(deployments []*Deployment, params OutParams) ([]*Deployment, []*Deployment) {
+	sort.Slice(deployments, func(i, j int) bool {
+		return deployments[i].GetID() > deployments[j].GetID()
+	})
+
+	var cutoff time.Time
+	if params.OlderThan != nil {
+		cutoff = time.Now().Add(-params.OlderThan.Duration)
+	}
+
+	kept := []*Deployment{}
+	expired := []*Deployment{}
+	newer := map[string]int{}
+
+	for _, deployment := range deployments {
+		env := deployment.GetEnvironment()
+		position := newer[env]
+		newer[env]++
+
+		switch {
+		case params.TransientOnly && !deployment.GetTransientEnvironment():
+		case params.KeepLast != nil && position < *params.KeepLast:
+		case params.OlderThan != nil && !deployment.GetCreatedAt().Before(cutoff):
+		default:
+			expired = append(expired, deployment)
+			continue
+		}
+
+		kept = append(kept, deployment)
+	}
+
+	return kept, expired
+}
+
+// cleanup marks deployments inactive and deletes them, using a bounded
+// number of concurrent requests. GitHub only deletes inactive deployments, so
+// each deployment is marked inactive before it is deleted.
+func (c *CleanupOutCommand) cleanup(ctx context.Context, inactivate, remove []int64, concurrency int) error {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	err := inParallel(len(inactivate), concurrency, func(i int) error {
+		_, err := c.github.CreateDeploymentStatus(ctx, inactivate[i], &DeploymentStatusRequest{
+			State: github.String("inactive"),
+		})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return inParallel(len(remove), concurrency, func(i int) error {
+		return c.github.DeleteDeployment(ctx, remove[i])
+	})
+}
+
+func joinIDs(ids []int64) string {
+	formatted := []string{}
+	for _, id := range ids {
+		formatted = append(formatted, strconv.FormatInt(id, 10))
+	}
+	return strings.Join(formatted, ",")
+}
@@ -0,0 +1,203 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"sort"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+	"github.com/ahume/github-deployment-resource/fakes"
+)
+
+var _ = Describe("Cleanup Out Command", func() {
+	var (
+		command      *resource.CleanupOutCommand
+		githubClient *fakes.FakeGitHub
+
+		sourcesDir string
+		request    resource.OutRequest
+	)
+
+	deployment := func(id int64, env string, age time.Duration, transient bool) *resource.Deployment {
+		return &resource.Deployment{
+			Deployment: &github.Deployment{
+				ID:          github.Int64(id),
+				Environment: github.String(env),
+				CreatedAt:   &github.Timestamp{Time: time.Now().Add(-age)},
+			},
+			TransientEnvironment: github.Bool(transient),
+		}
+	}
+
+	inactivatedIDs := func() []int64 {
+		ids := []int64{}
+		for i := 0; i < githubClient.CreateDeploymentStatusCallCount(); i++ {
+			_, id, status := githubClient.CreateDeploymentStatusArgsForCall(i)
+			Ω(status.State).Should(Equal(github.String("inactive")))
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+
+	deletedIDs := func() []int64 {
+		ids := []int64{}
+		for i := 0; i < githubClient.DeleteDeploymentCallCount(); i++ {
+			_, id := githubClient.DeleteDeploymentArgsForCall(i)
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		return ids
+	}
+
+	BeforeEach(func() {
+		githubClient = &fakes.FakeGitHub{}
+		command = resource.NewCleanupOutCommand(githubClient, ioutil.Discard)
+
+		githubClient.ListDeploymentsReturns([]*resource.Deployment{
+			deployment(1, "production", 72*time.Hour, false),
+			deployment(2, "pr-1", 48*time.Hour, true),
+			deployment(3, "production", 48*time.Hour, false),
+			deployment(4, "pr-1", 2*time.Hour, true),
+			deployment(5, "production", time.Hour, false),
+			deployment(6, "pr-1", time.Minute, true),
+		}, nil)
+
+		githubClient.GetLatestDeploymentStatusStub = func(ctx context.Context, id int64) (*github.DeploymentStatus, error) {
+			if id == 1 {
+				return &github.DeploymentStatus{State: github.String("inactive")}, nil
+			}
+			return &github.DeploymentStatus{State: github.String("success")}, nil
+		}
+
+		request = resource.OutRequest{
+			Params: resource.OutParams{
+				Type: github.String("cleanup"),
+			},
+		}
+	})
+
+	It("keeps the latest deployments to each environment", func() {
+		request.Params.KeepLast = github.Int(1)
+
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(inactivatedIDs()).Should(Equal([]int64{2, 3, 4}))
+		Ω(deletedIDs()).Should(BeEmpty())
+
+		Ω(outResponse.Version).Should(Equal(resource.Version{ID: "6", Cleanup: "true"}))
+		Ω(outResponse.Metadata).Should(ConsistOf(
+			resource.MetadataPair{Name: "dry_run", Value: "false"},
+			resource.MetadataPair{Name: "kept", Value: "2"},
+			resource.MetadataPair{Name: "inactivated", Value: "4,3,2"},
+			resource.MetadataPair{Name: "deleted", Value: ""},
+		))
+	})
+
+	It("marks the version as a cleanup when every deployment is deleted", func() {
+		request.Params.KeepLast = github.Int(0)
+		request.Params.Delete = true
+
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(deletedIDs()).Should(Equal([]int64{1, 2, 3, 4, 5, 6}))
+		Ω(outResponse.Version).Should(Equal(resource.Version{ID: "6", Cleanup: "true"}))
+	})
+
+	It("only removes deployments older than older_than", func() {
+		request.Params.OlderThan = &resource.Duration{Duration: 24 * time.Hour}
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(inactivatedIDs()).Should(Equal([]int64{2, 3}))
+	})
+
+	It("requires both conditions when keep_last and older_than are set", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.OlderThan = &resource.Duration{Duration: 90 * time.Minute}
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(inactivatedIDs()).Should(Equal([]int64{2, 3, 4}))
+	})
+
+	It("only removes deployments to transient environments when transient_only is set", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.TransientOnly = true
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(inactivatedIDs()).Should(Equal([]int64{2, 4}))
+	})
+
+	It("only lists deployments to the given environment", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.Environment = github.String("production")
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, opts := githubClient.ListDeploymentsArgsForCall(0)
+		Ω(opts.Environment).Should(Equal("production"))
+	})
+
+	It("deletes the expired deployments when delete is set", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.Delete = true
+
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(inactivatedIDs()).Should(Equal([]int64{2, 3, 4}))
+		Ω(deletedIDs()).Should(Equal([]int64{1, 2, 3, 4}))
+		Ω(outResponse.Metadata).Should(ContainElement(
+			resource.MetadataPair{Name: "deleted", Value: "4,3,2,1"},
+		))
+	})
+
+	It("does not change anything in a dry run", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.Delete = true
+		request.Params.DryRun = true
+
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(0))
+		Ω(githubClient.DeleteDeploymentCallCount()).Should(Equal(0))
+		Ω(outResponse.Metadata).Should(ConsistOf(
+			resource.MetadataPair{Name: "dry_run", Value: "true"},
+			resource.MetadataPair{Name: "kept", Value: "2"},
+			resource.MetadataPair{Name: "inactivated", Value: "4,3,2"},
+			resource.MetadataPair{Name: "deleted", Value: "4,3,2,1"},
+		))
+	})
+
+	It("does not delete anything when marking deployments inactive fails", func() {
+		request.Params.KeepLast = github.Int(1)
+		request.Params.Delete = true
+		githubClient.CreateDeploymentStatusReturns(nil, errors.New("disaster"))
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).Should(MatchError("disaster"))
+
+		Ω(githubClient.DeleteDeploymentCallCount()).Should(Equal(0))
+	})
+
+	It("requires keep_last or older_than", func() {
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).Should(MatchError("one of keep_last or older_than is required"))
+	})
+})
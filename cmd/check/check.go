@@ -11,13 +11,16 @@ func main() {
   request := resource.NewCheckRequest()
   inputRequest(&request)
 
-  github, err := resource.NewGitHubClient(request.Source)
+  ctx, cancel := resource.NewRunContext(request.Source)
+  defer cancel()
+
+  github, err := resource.NewGitHubClient(ctx, request.Source, os.Stderr)
   if err != nil {
     resource.Fatal("constructing github client", err)
   }
 
   command := resource.NewCheckCommand(github, os.Stderr)
-  response, err := command.Run(request)
+  response, err := command.Run(ctx, request)
   if err != nil {
     resource.Fatal("running command", err)
   }
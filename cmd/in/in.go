@@ -18,13 +18,16 @@ func main() {
 
   destDir := os.Args[1]
 
-  github, err := resource.NewGitHubClient(request.Source)
+  ctx, cancel := resource.NewRunContext(request.Source)
+  defer cancel()
+
+  github, err := resource.NewGitHubClient(ctx, request.Source, os.Stderr)
   if err != nil {
     resource.Fatal("constructing github client", err)
   }
 
   command := resource.NewInCommand(github, os.Stderr)
-  response, err := command.Run(destDir, request)
+  response, err := command.Run(ctx, destDir, request)
   if err != nil {
     resource.Fatal("running command", err)
   }
@@ -2,6 +2,7 @@ package main
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 
 	resource "github.com/ahume/github-deployment-resource"
@@ -18,26 +19,43 @@ func main() {
 
 	sourceDir := os.Args[1]
 
-	github, err := resource.NewGitHubClient(request.Source)
+	if err := request.Params.ResolveAndValidate(sourceDir); err != nil {
+		resource.Fatal("validating params", err)
+	}
+
+	ctx, cancel := resource.NewRunContext(request.Source)
+	defer cancel()
+
+	github, err := resource.NewGitHubClient(ctx, request.Source, os.Stderr)
 	if err != nil {
 		resource.Fatal("constructing github client", err)
 	}
 
-	if *request.Params.Type == "deployment" {
+	var response resource.OutResponse
+	switch *request.Params.Type {
+	case "deployment":
 		command := resource.NewDeploymentOutCommand(github, os.Stderr)
-		response, err := command.Run(sourceDir, request)
-		if err != nil {
-			resource.Fatal("running command", err)
-		}
-		outputResponse(response)
-	} else {
+		response, err = command.Run(ctx, sourceDir, request)
+	case "delete":
+		command := resource.NewDeleteOutCommand(github, os.Stderr)
+		response, err = command.Run(ctx, sourceDir, request)
+	case "cleanup":
+		command := resource.NewCleanupOutCommand(github, os.Stderr)
+		response, err = command.Run(ctx, sourceDir, request)
+	case "environment":
+		command := resource.NewEnvironmentOutCommand(github, os.Stderr)
+		response, err = command.Run(ctx, sourceDir, request)
+	case "status":
 		command := resource.NewOutCommand(github, os.Stderr)
-		response, err := command.Run(sourceDir, request)
-		if err != nil {
-			resource.Fatal("running command", err)
-		}
-		outputResponse(response)
+		response, err = command.Run(ctx, sourceDir, request)
+	default:
+		err = fmt.Errorf("unknown type %q", *request.Params.Type)
 	}
+	if err != nil {
+		resource.Fatal("running command", err)
+	}
+
+	outputResponse(response)
 }
 
 func inputRequest(request *resource.OutRequest) {
@@ -0,0 +1,86 @@
+package resource
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// debugTransport logs every request to GitHub and its response, with
+// credentials redacted.
+type debugTransport struct {
+	base   http.RoundTripper
+	writer io.Writer
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "--> %s %s\n", req.Method, req.URL)
+	writeHeaders(&out, req.Header)
+
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err == nil {
+			contents, _ := ioutil.ReadAll(body)
+			body.Close()
+			writeBody(&out, contents)
+		}
+	}
+
+	start := time.Now()
+	res, err := t.base.RoundTrip(req)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	if err != nil {
+		fmt.Fprintf(&out, "<-- %s %s failed after %s: %s\n", req.Method, req.URL, elapsed, err)
+		fmt.Fprint(t.writer, Redact(out.String()))
+		return nil, err
+	}
+
+	fmt.Fprintf(&out, "<-- %s %s %s (%s)\n", res.Status, req.Method, req.URL, elapsed)
+	if limit := res.Header.Get("X-RateLimit-Limit"); limit != "" {
+		fmt.Fprintf(&out, "    rate limit: %s of %s remaining, resets at %s\n",
+			res.Header.Get("X-RateLimit-Remaining"), limit, res.Header.Get("X-RateLimit-Reset"))
+	}
+
+	contents, readErr := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	res.Body = ioutil.NopCloser(bytes.NewReader(contents))
+	writeBody(&out, contents)
+
+	fmt.Fprint(t.writer, Redact(out.String()))
+
+	if readErr != nil {
+		return nil, readErr
+	}
+	return res, nil
+}
+
+func writeHeaders(out io.Writer, header http.Header) {
+	names := []string{}
+	for name := range header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		value := strings.Join(header[name], ", ")
+		if name == "Authorization" {
+			value = redacted
+		}
+		fmt.Fprintf(out, "    %s: %s\n", name, value)
+	}
+}
+
+func writeBody(out io.Writer, body []byte) {
+	if len(body) == 0 {
+		return
+	}
+	fmt.Fprintf(out, "    %s\n", strings.Replace(strings.TrimSpace(string(body)), "\n", "\n    ", -1))
+}
@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/google/go-github/v28/github"
+)
+
+type DeleteOutCommand struct {
+	github GitHub
+	writer io.Writer
+}
+
+func NewDeleteOutCommand(github GitHub, writer io.Writer) *DeleteOutCommand {
+	return &DeleteOutCommand{
+		github: github,
+		writer: writer,
+	}
+}
+
+func (c *DeleteOutCommand) Run(ctx context.Context, sourceDir string, request OutRequest) (OutResponse, error) {
+	if request.Params.ID == nil {
+		return OutResponse{}, errors.New("id is a required parameter")
+	}
+
+	idInt, err := strconv.ParseInt(*request.Params.ID, 10, 64)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	fmt.Fprintln(c.writer, "getting deployment")
+	deployment, err := c.github.GetDeployment(ctx, idInt)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	fmt.Fprintln(c.writer, "getting deployment statuses list")
+	statuses, err := c.github.ListDeploymentStatuses(ctx, *deployment.ID)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	// GitHub refuses to delete a deployment that is still active.
+	if len(statuses) == 0 || statuses[0].GetState() != "inactive" {
+		fmt.Fprintln(c.writer, "marking deployment inactive")
+		status, err := c.github.CreateDeploymentStatus(ctx, *deployment.ID, &DeploymentStatusRequest{
+			State: github.String("inactive"),
+		})
+		if err != nil {
+			return OutResponse{}, err
+		}
+		statuses = append([]*github.DeploymentStatus{status}, statuses...)
+	}
+
+	fmt.Fprintln(c.writer, "deleting deployment")
+	err = c.github.DeleteDeployment(ctx, *deployment.ID)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	metadata := metadataFromDeployment(deployment, statuses)
+	metadata = append(metadata, MetadataPair{
+		Name:  "deleted",
+		Value: "true",
+	})
+
+	return OutResponse{
+		Version: Version{
+			ID:      strconv.FormatInt(*deployment.ID, 10),
+			Deleted: "true",
+		},
+		Metadata: metadata,
+	}, nil
+}
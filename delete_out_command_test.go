@@ -0,0 +1,138 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+	"github.com/ahume/github-deployment-resource/fakes"
+)
+
+var _ = Describe("Delete Out Command", func() {
+	var (
+		command      *resource.DeleteOutCommand
+		githubClient *fakes.FakeGitHub
+
+		sourcesDir string
+		request    resource.OutRequest
+	)
+
+	BeforeEach(func() {
+		githubClient = &fakes.FakeGitHub{}
+		command = resource.NewDeleteOutCommand(githubClient, ioutil.Discard)
+
+		githubClient.GetDeploymentReturns(&resource.Deployment{Deployment: &github.Deployment{
+			ID:          github.Int64(1234),
+			Ref:         github.String("feature"),
+			SHA:         github.String("12345"),
+			Environment: github.String("pr-123"),
+			Creator: &github.User{
+				Login: github.String("theboss"),
+			},
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+		}}, nil)
+
+		githubClient.CreateDeploymentStatusReturns(&github.DeploymentStatus{
+			ID:        github.Int64(13),
+			State:     github.String("inactive"),
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 20, 20, 20, 0, time.UTC)},
+		}, nil)
+
+		request = resource.OutRequest{
+			Params: resource.OutParams{
+				Type: github.String("delete"),
+				ID:   github.String("1234"),
+			},
+		}
+	})
+
+	Context("when the deployment is still active", func() {
+		BeforeEach(func() {
+			githubClient.ListDeploymentStatusesReturns([]*github.DeploymentStatus{
+				{ID: github.Int64(12), State: github.String("success")},
+			}, nil)
+		})
+
+		It("marks the deployment inactive and then deletes it", func() {
+			_, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(1))
+			_, id, status := githubClient.CreateDeploymentStatusArgsForCall(0)
+			Ω(id).Should(Equal(int64(1234)))
+			Ω(status.State).Should(Equal(github.String("inactive")))
+
+			Ω(githubClient.DeleteDeploymentCallCount()).Should(Equal(1))
+			_, id = githubClient.DeleteDeploymentArgsForCall(0)
+			Ω(id).Should(Equal(int64(1234)))
+
+			invocations := githubClient.Invocations()
+			Ω(invocations).Should(HaveKey("CreateDeploymentStatus"))
+			Ω(invocations).Should(HaveKey("DeleteDeployment"))
+		})
+
+		It("reports what was removed", func() {
+			outResponse, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(outResponse.Version).Should(Equal(resource.Version{ID: "1234", Deleted: "true"}))
+			Ω(outResponse.Metadata).Should(ConsistOf(
+				resource.MetadataPair{Name: "id", Value: "1234"},
+				resource.MetadataPair{Name: "ref", Value: "feature"},
+				resource.MetadataPair{Name: "sha", Value: "12345"},
+				resource.MetadataPair{Name: "environment", Value: "pr-123"},
+				resource.MetadataPair{Name: "creator", Value: "theboss"},
+				resource.MetadataPair{Name: "created_at", Value: "2016-01-20 15:15:15"},
+				resource.MetadataPair{Name: "status_id", Value: "13"},
+				resource.MetadataPair{Name: "status", Value: "inactive"},
+				resource.MetadataPair{Name: "status_created_at", Value: "2016-01-20 20:20:20"},
+				resource.MetadataPair{Name: "status_count", Value: "2"},
+				resource.MetadataPair{Name: "deleted", Value: "true"},
+			))
+		})
+	})
+
+	Context("when the deployment is already inactive", func() {
+		BeforeEach(func() {
+			githubClient.ListDeploymentStatusesReturns([]*github.DeploymentStatus{
+				{ID: github.Int64(12), State: github.String("inactive")},
+			}, nil)
+		})
+
+		It("deletes it without creating another status", func() {
+			_, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(0))
+			Ω(githubClient.DeleteDeploymentCallCount()).Should(Equal(1))
+		})
+	})
+
+	Context("when marking the deployment inactive fails", func() {
+		BeforeEach(func() {
+			githubClient.ListDeploymentStatusesReturns([]*github.DeploymentStatus{}, nil)
+			githubClient.CreateDeploymentStatusReturns(nil, errors.New("disaster"))
+		})
+
+		It("does not try to delete it", func() {
+			_, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).Should(MatchError("disaster"))
+
+			Ω(githubClient.DeleteDeploymentCallCount()).Should(Equal(0))
+		})
+	})
+
+	It("requires an id", func() {
+		_, err := command.Run(context.Background(), sourcesDir, resource.OutRequest{
+			Params: resource.OutParams{},
+		})
+		Ω(err).Should(MatchError("id is a required parameter"))
+	})
+})
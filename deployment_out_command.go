@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -25,7 +26,7 @@ func NewDeploymentOutCommand(github GitHub, writer io.Writer) *DeploymentOutComm
 	}
 }
 
-func (c *DeploymentOutCommand) Run(sourceDir string, request OutRequest) (OutResponse, error) {
+func (c *DeploymentOutCommand) Run(ctx context.Context, sourceDir string, request OutRequest) (OutResponse, error) {
 	if request.Params.Ref == nil {
 		return OutResponse{}, errors.New("ref is a required parameter")
 	}
@@ -41,9 +42,8 @@ func (c *DeploymentOutCommand) Run(sourceDir string, request OutRequest) (OutRes
 		"build_job_name":      os.Getenv("BUILD_JOB_NAME"),
 		"build_pipeline_name": os.Getenv("BUILD_PIPELINE_NAME"),
 		"build_team_name":     os.Getenv("BUILD_TEAM_NAME"),
-		"build_url": fmt.Sprintf("%v/teams/%v/pipelines/%v/jobs/%v/builds/%v",
-			os.Getenv("ATC_EXTERNAL_URL"), os.Getenv("BUILD_TEAM_NAME"), os.Getenv("BUILD_PIPELINE_NAME"), os.Getenv("BUILD_JOB_NAME"), os.Getenv("BUILD_NAME")),
-		"atc_external_url": os.Getenv("ATC_EXTERNAL_URL"),
+		"build_url":           buildURL(),
+		"atc_external_url":    os.Getenv("ATC_EXTERNAL_URL"),
 	}
 
 	if request.Params.Payload != nil {
@@ -69,19 +69,68 @@ func (c *DeploymentOutCommand) Run(sourceDir string, request OutRequest) (OutRes
 	if request.Params.AutoMerge != nil {
 		newDeployment.AutoMerge = request.Params.AutoMerge
 	}
+	if request.Params.RequiredContexts != nil {
+		newDeployment.RequiredContexts = request.Params.RequiredContexts
+	}
+	if request.Params.DefaultRequiredContexts {
+		newDeployment.RequiredContexts = nil
+	}
+	if request.Params.TransientEnvironment != nil {
+		newDeployment.TransientEnvironment = request.Params.TransientEnvironment
+	}
+	if request.Params.ProductionEnvironment != nil {
+		newDeployment.ProductionEnvironment = request.Params.ProductionEnvironment
+	}
 
 	fmt.Fprintln(c.writer, "creating deployment")
-	deployment, err := c.github.CreateDeployment(newDeployment)
+	deployment, err := c.github.CreateDeployment(ctx, newDeployment)
 	if err != nil {
 		return OutResponse{}, err
 	}
 
+	statuses := []*github.DeploymentStatus{}
+	if request.Params.InitialStatus != nil {
+		newStatus := &DeploymentStatusRequest{
+			State:       request.Params.InitialStatus,
+			Description: request.Params.InitialDescription,
+			LogURL:      request.Params.InitialLogURL,
+		}
+
+		fmt.Fprintln(c.writer, "creating initial deployment status")
+		status, err := c.github.CreateDeploymentStatus(ctx, *deployment.ID, newStatus)
+		if err != nil {
+			return OutResponse{}, fmt.Errorf("deployment %d was created, but its initial status was not: %v", *deployment.ID, err)
+		}
+		statuses = append(statuses, status)
+	}
+
 	return OutResponse{
 		Version:  Version{ID: strconv.FormatInt(*deployment.ID, 10)},
-		Metadata: metadataFromDeployment(deployment, []*github.DeploymentStatus{}),
+		Metadata: metadataFromDeployment(deployment, statuses),
 	}, nil
 }
 
+// RequiredContextsError is returned when GitHub refuses to create a
+// deployment because some of its required contexts have not succeeded.
+type RequiredContextsError struct {
+	Ref      string
+	Contexts []ContextState
+}
+
+// ContextState is the state of a required context, as GitHub reports it.
+type ContextState struct {
+	Context string `json:"context"`
+	State   string `json:"state"`
+}
+
+func (e *RequiredContextsError) Error() string {
+	lines := []string{fmt.Sprintf("commit status checks have not passed for %s:", e.Ref)}
+	for _, state := range e.Contexts {
+		lines = append(lines, fmt.Sprintf("  %s: %s", state.Context, state.State))
+	}
+	return strings.Join(lines, "\n")
+}
+
 func (c *DeploymentOutCommand) fileContents(path string) (string, error) {
 	contents, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -1,6 +1,8 @@
 package resource_test
 
 import (
+	"context"
+	"errors"
 	"io/ioutil"
 	"os"
 	"time"
@@ -42,7 +44,7 @@ var _ = Describe("Deployment Out Command", func() {
 		Context("with strings in params", func() {
 			Context("when all possible params are present", func() {
 				BeforeEach(func() {
-					githubClient.CreateDeploymentReturns(&github.Deployment{
+					githubClient.CreateDeploymentReturns(&resource.Deployment{Deployment: &github.Deployment{
 						ID:          github.Int64(1),
 						Ref:         github.String("ref"),
 						SHA:         github.String("1234"),
@@ -52,8 +54,8 @@ var _ = Describe("Deployment Out Command", func() {
 						Creator: &github.User{
 							Login: github.String("theboss"),
 						},
-						CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
-					}, nil)
+						CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+					}}, nil)
 
 					request = resource.OutRequest{
 						Params: resource.OutParams{
@@ -69,11 +71,11 @@ var _ = Describe("Deployment Out Command", func() {
 					}
 				})
 				It("creates a new deployment", func() {
-					_, err := command.Run(sourcesDir, request)
+					_, err := command.Run(context.Background(), sourcesDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(githubClient.CreateDeploymentCallCount()).Should(Equal(1))
-					deployment := githubClient.CreateDeploymentArgsForCall(0)
+					_, deployment := githubClient.CreateDeploymentArgsForCall(0)
 
 					Ω(deployment.Ref).Should(Equal(github.String("ref")))
 					Ω(deployment.Task).Should(Equal(github.String("task")))
@@ -84,7 +86,7 @@ var _ = Describe("Deployment Out Command", func() {
 				})
 
 				It("returns some metadata", func() {
-					outResponse, err := command.Run(sourcesDir, request)
+					outResponse, err := command.Run(context.Background(), sourcesDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(outResponse.Metadata).Should(ConsistOf(
@@ -101,7 +103,7 @@ var _ = Describe("Deployment Out Command", func() {
 				})
 
 				It("returns the new version number", func() {
-					outResponse, err := command.Run(sourcesDir, request)
+					outResponse, err := command.Run(context.Background(), sourcesDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(outResponse.Version).Should(Equal(
@@ -114,15 +116,15 @@ var _ = Describe("Deployment Out Command", func() {
 
 			Context("when only required params are present", func() {
 				BeforeEach(func() {
-					githubClient.CreateDeploymentReturns(&github.Deployment{
+					githubClient.CreateDeploymentReturns(&resource.Deployment{Deployment: &github.Deployment{
 						ID:  github.Int64(1),
 						Ref: github.String("ref"),
 						SHA: github.String("1234"),
 						Creator: &github.User{
 							Login: github.String("theboss"),
 						},
-						CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
-					}, nil)
+						CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+					}}, nil)
 
 					request = resource.OutRequest{
 						Params: resource.OutParams{
@@ -132,7 +134,7 @@ var _ = Describe("Deployment Out Command", func() {
 				})
 
 				It("returns some metadata", func() {
-					outResponse, err := command.Run(sourcesDir, request)
+					outResponse, err := command.Run(context.Background(), sourcesDir, request)
 					Ω(err).ShouldNot(HaveOccurred())
 
 					Ω(outResponse.Metadata).Should(ConsistOf(
@@ -146,6 +148,109 @@ var _ = Describe("Deployment Out Command", func() {
 				})
 			})
 
+			Context("with deployment options", func() {
+				BeforeEach(func() {
+					githubClient.CreateDeploymentReturns(&resource.Deployment{Deployment: &github.Deployment{
+						ID: github.Int64(1),
+					}}, nil)
+
+					request = resource.OutRequest{
+						Params: resource.OutParams{
+							Ref: github.String("master"),
+						},
+					}
+				})
+
+				It("sends the environment flags", func() {
+					request.Params.TransientEnvironment = github.Bool(true)
+					request.Params.ProductionEnvironment = github.Bool(false)
+
+					_, err := command.Run(context.Background(), sourcesDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, deployment := githubClient.CreateDeploymentArgsForCall(0)
+					Ω(deployment.TransientEnvironment).Should(Equal(github.Bool(true)))
+					Ω(deployment.ProductionEnvironment).Should(Equal(github.Bool(false)))
+				})
+
+				It("does not create a status by default", func() {
+					_, err := command.Run(context.Background(), sourcesDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(0))
+				})
+
+				Context("with an initial status", func() {
+					BeforeEach(func() {
+						request.Params.InitialStatus = github.String("pending")
+						request.Params.InitialDescription = github.String("waiting to deploy")
+						request.Params.InitialLogURL = github.String("https://ci.example.com/builds/1")
+
+						githubClient.CreateDeploymentStatusReturns(&github.DeploymentStatus{
+							ID:        github.Int64(7),
+							State:     github.String("pending"),
+							CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+						}, nil)
+					})
+
+					It("creates the status for the new deployment", func() {
+						_, err := command.Run(context.Background(), sourcesDir, request)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(1))
+						_, id, status := githubClient.CreateDeploymentStatusArgsForCall(0)
+						Ω(id).Should(Equal(int64(1)))
+						Ω(status.State).Should(Equal(github.String("pending")))
+						Ω(status.Description).Should(Equal(github.String("waiting to deploy")))
+						Ω(status.LogURL).Should(Equal(github.String("https://ci.example.com/builds/1")))
+					})
+
+					It("includes the status in the metadata", func() {
+						output, err := command.Run(context.Background(), sourcesDir, request)
+						Ω(err).ShouldNot(HaveOccurred())
+
+						Ω(output.Metadata).Should(ContainElement(resource.MetadataPair{Name: "status_id", Value: "7"}))
+						Ω(output.Metadata).Should(ContainElement(resource.MetadataPair{Name: "status", Value: "pending"}))
+						Ω(output.Metadata).Should(ContainElement(resource.MetadataPair{Name: "status_count", Value: "1"}))
+					})
+
+					It("reports the deployment when the status cannot be created", func() {
+						githubClient.CreateDeploymentStatusReturns(nil, errors.New("disaster"))
+
+						_, err := command.Run(context.Background(), sourcesDir, request)
+						Ω(err).Should(MatchError("deployment 1 was created, but its initial status was not: disaster"))
+					})
+				})
+
+				It("skips the commit status checks by default", func() {
+					_, err := command.Run(context.Background(), sourcesDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, deployment := githubClient.CreateDeploymentArgsForCall(0)
+					Ω(deployment.RequiredContexts).Should(Equal(&[]string{}))
+				})
+
+				It("sends the given contexts", func() {
+					request.Params.RequiredContexts = &[]string{"ci/build", "ci/lint"}
+
+					_, err := command.Run(context.Background(), sourcesDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, deployment := githubClient.CreateDeploymentArgsForCall(0)
+					Ω(deployment.RequiredContexts).Should(Equal(&[]string{"ci/build", "ci/lint"}))
+				})
+
+				It("lets GitHub check every context when set to default", func() {
+					request.Params.DefaultRequiredContexts = true
+
+					_, err := command.Run(context.Background(), sourcesDir, request)
+					Ω(err).ShouldNot(HaveOccurred())
+
+					_, deployment := githubClient.CreateDeploymentArgsForCall(0)
+					Ω(deployment.RequiredContexts).Should(BeNil())
+				})
+			})
+
 			Context("when required param ref is missing", func() {
 				BeforeEach(func() {
 					request = resource.OutRequest{
@@ -154,7 +259,7 @@ var _ = Describe("Deployment Out Command", func() {
 				})
 
 				It("returns appropriate error", func() {
-					_, err := command.Run(sourcesDir, request)
+					_, err := command.Run(context.Background(), sourcesDir, request)
 					Ω(err).Should(MatchError("ref is a required parameter"))
 				})
 			})
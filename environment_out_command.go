@@ -0,0 +1,190 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type EnvironmentOutCommand struct {
+	environments Environments
+	writer       io.Writer
+}
+
+func NewEnvironmentOutCommand(environments Environments, writer io.Writer) *EnvironmentOutCommand {
+	return &EnvironmentOutCommand{
+		environments: environments,
+		writer:       writer,
+	}
+}
+
+func (c *EnvironmentOutCommand) Run(ctx context.Context, sourceDir string, request OutRequest) (OutResponse, error) {
+	params := request.Params
+	if params.Environment == nil || *params.Environment == "" {
+		return OutResponse{}, errors.New("environment is a required parameter")
+	}
+	name := *params.Environment
+
+	if params.Delete {
+		return c.delete(ctx, name)
+	}
+
+	environmentRequest := &EnvironmentRequest{
+		WaitTimer: params.WaitTimer,
+		Reviewers: params.Reviewers,
+	}
+	if params.DeploymentBranches != nil {
+		environmentRequest.DeploymentBranchPolicy = &BranchPolicySettings{
+			ProtectedBranches:    params.DeploymentBranches.Protected,
+			CustomBranchPolicies: !params.DeploymentBranches.Protected,
+		}
+	}
+
+	fmt.Fprintf(c.writer, "configuring environment %s\n", name)
+	environment, err := c.environments.CreateOrUpdateEnvironment(ctx, name, environmentRequest)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	if params.DeploymentBranches != nil && !params.DeploymentBranches.Protected {
+		err = c.syncBranchPolicies(ctx, name, params.DeploymentBranches.Patterns)
+		if err != nil {
+			return OutResponse{}, err
+		}
+	}
+
+	return OutResponse{
+		Version:  environmentVersion(environment),
+		Metadata: metadataFromEnvironment(environment, params.DeploymentBranches),
+	}, nil
+}
+
+func (c *EnvironmentOutCommand) delete(ctx context.Context, name string) (OutResponse, error) {
+	fmt.Fprintf(c.writer, "getting environment %s\n", name)
+	environment, err := c.environments.GetEnvironment(ctx, name)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	fmt.Fprintf(c.writer, "deleting environment %s\n", name)
+	err = c.environments.DeleteEnvironment(ctx, name)
+	if err != nil {
+		return OutResponse{}, err
+	}
+
+	metadata := metadataFromEnvironment(environment, nil)
+	metadata = append(metadata, MetadataPair{
+		Name:  "deleted",
+		Value: "true",
+	})
+
+	version := environmentVersion(environment)
+	version.Deleted = "true"
+
+	return OutResponse{
+		Version:  version,
+		Metadata: metadata,
+	}, nil
+}
+
+// environmentVersion identifies an environment without a deployment ID, so
+// that the implicit get does not mistake it for a deployment.
+func environmentVersion(environment *Environment) Version {
+	return Version{
+		Environment:   environment.GetName(),
+		EnvironmentID: strconv.FormatInt(environment.GetID(), 10),
+	}
+}
+
+// syncBranchPolicies creates and deletes deployment branch policies so that
+// the environment only allows branches matching the given patterns.
+func (c *EnvironmentOutCommand) syncBranchPolicies(ctx context.Context, name string, patterns []string) error {
+	fmt.Fprintln(c.writer, "getting deployment branch policies")
+	policies, err := c.environments.ListDeploymentBranchPolicies(ctx, name)
+	if err != nil {
+		return err
+	}
+
+	existing := map[string]bool{}
+	for _, policy := range policies {
+		existing[policy.GetName()] = true
+	}
+
+	wanted := map[string]bool{}
+	for _, pattern := range patterns {
+		wanted[pattern] = true
+		if existing[pattern] {
+			continue
+		}
+
+		fmt.Fprintf(c.writer, "adding deployment branch policy %s\n", pattern)
+		_, err = c.environments.CreateDeploymentBranchPolicy(ctx, name, pattern)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, policy := range policies {
+		if wanted[policy.GetName()] {
+			continue
+		}
+
+		fmt.Fprintf(c.writer, "removing deployment branch policy %s\n", policy.GetName())
+		err = c.environments.DeleteDeploymentBranchPolicy(ctx, name, policy.GetID())
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func metadataFromEnvironment(environment *Environment, branches *DeploymentBranches) []MetadataPair {
+	metadata := []MetadataPair{
+		{Name: "environment_id", Value: strconv.FormatInt(environment.GetID(), 10)},
+		{Name: "environment", Value: environment.GetName()},
+	}
+
+	if environment.HTMLURL != nil {
+		metadata = append(metadata, MetadataPair{
+			Name:  "environment_url",
+			Value: *environment.HTMLURL,
+		})
+	}
+
+	for _, rule := range environment.ProtectionRules {
+		if rule.WaitTimer != nil {
+			metadata = append(metadata, MetadataPair{
+				Name:  "wait_timer",
+				Value: strconv.Itoa(*rule.WaitTimer),
+			})
+		}
+
+		if len(rule.Reviewers) > 0 {
+			reviewers := []string{}
+			for _, reviewer := range rule.Reviewers {
+				reviewers = append(reviewers, reviewer.GetName())
+			}
+			metadata = append(metadata, MetadataPair{
+				Name:  "reviewers",
+				Value: strings.Join(reviewers, ","),
+			})
+		}
+	}
+
+	if branches != nil {
+		value := "protected"
+		if !branches.Protected {
+			value = strings.Join(branches.Patterns, ",")
+		}
+		metadata = append(metadata, MetadataPair{
+			Name:  "deployment_branches",
+			Value: value,
+		})
+	}
+
+	return metadata
+}
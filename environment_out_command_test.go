@@ -0,0 +1,174 @@
+package resource_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+	"github.com/ahume/github-deployment-resource/fakes"
+)
+
+var _ = Describe("Environment Out Command", func() {
+	var (
+		command      *resource.EnvironmentOutCommand
+		environments *fakes.FakeEnvironments
+
+		sourcesDir string
+		request    resource.OutRequest
+	)
+
+	BeforeEach(func() {
+		environments = &fakes.FakeEnvironments{}
+		command = resource.NewEnvironmentOutCommand(environments, ioutil.Discard)
+
+		environment := &resource.Environment{
+			ID:      github.Int64(99),
+			Name:    github.String("pr-123"),
+			HTMLURL: github.String("https://github.com/owner/repo/deployments/activity_log?environments_filter=pr-123"),
+			ProtectionRules: []*resource.ProtectionRule{
+				{Type: github.String("wait_timer"), WaitTimer: github.Int(5)},
+				{Type: github.String("required_reviewers"), Reviewers: []*resource.RequiredReviewer{
+					{Type: github.String("User"), Reviewer: &resource.Reviewer{Login: github.String("theboss")}},
+					{Type: github.String("Team"), Reviewer: &resource.Reviewer{Slug: github.String("ops")}},
+				}},
+			},
+		}
+		environments.CreateOrUpdateEnvironmentReturns(environment, nil)
+		environments.GetEnvironmentReturns(environment, nil)
+
+		request = resource.OutRequest{
+			Params: resource.OutParams{
+				Type:        github.String("environment"),
+				Environment: github.String("pr-123"),
+				WaitTimer:   github.Int(5),
+				Reviewers: []*resource.EnvironmentReviewer{
+					{Type: github.String("User"), ID: github.Int64(1)},
+					{Type: github.String("Team"), ID: github.Int64(2)},
+				},
+			},
+		}
+	})
+
+	It("creates or updates the environment", func() {
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(environments.CreateOrUpdateEnvironmentCallCount()).Should(Equal(1))
+		_, name, environmentRequest := environments.CreateOrUpdateEnvironmentArgsForCall(0)
+		Ω(name).Should(Equal("pr-123"))
+		Ω(environmentRequest.WaitTimer).Should(Equal(github.Int(5)))
+		Ω(environmentRequest.Reviewers).Should(Equal(request.Params.Reviewers))
+		Ω(environmentRequest.DeploymentBranchPolicy).Should(BeNil())
+
+		Ω(environments.ListDeploymentBranchPoliciesCallCount()).Should(Equal(0))
+
+		Ω(outResponse.Version).Should(Equal(resource.Version{Environment: "pr-123", EnvironmentID: "99"}))
+		Ω(outResponse.Metadata).Should(ConsistOf(
+			resource.MetadataPair{Name: "environment_id", Value: "99"},
+			resource.MetadataPair{Name: "environment", Value: "pr-123"},
+			resource.MetadataPair{Name: "environment_url", Value: "https://github.com/owner/repo/deployments/activity_log?environments_filter=pr-123"},
+			resource.MetadataPair{Name: "wait_timer", Value: "5"},
+			resource.MetadataPair{Name: "reviewers", Value: "theboss,ops"},
+		))
+	})
+
+	It("only allows protected branches to deploy", func() {
+		request.Params.DeploymentBranches = &resource.DeploymentBranches{Protected: true}
+
+		outResponse, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, _, environmentRequest := environments.CreateOrUpdateEnvironmentArgsForCall(0)
+		Ω(environmentRequest.DeploymentBranchPolicy).Should(Equal(&resource.BranchPolicySettings{
+			ProtectedBranches: true,
+		}))
+		Ω(environments.ListDeploymentBranchPoliciesCallCount()).Should(Equal(0))
+		Ω(outResponse.Metadata).Should(ContainElement(
+			resource.MetadataPair{Name: "deployment_branches", Value: "protected"},
+		))
+	})
+
+	Context("with deployment branch patterns", func() {
+		BeforeEach(func() {
+			request.Params.DeploymentBranches = &resource.DeploymentBranches{
+				Patterns: []string{"main", "release/*"},
+			}
+
+			environments.ListDeploymentBranchPoliciesReturns([]*resource.DeploymentBranchPolicy{
+				{ID: github.Int64(1), Name: github.String("main")},
+				{ID: github.Int64(2), Name: github.String("feature/*")},
+			}, nil)
+		})
+
+		It("uses custom branch policies", func() {
+			_, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, _, environmentRequest := environments.CreateOrUpdateEnvironmentArgsForCall(0)
+			Ω(environmentRequest.DeploymentBranchPolicy).Should(Equal(&resource.BranchPolicySettings{
+				CustomBranchPolicies: true,
+			}))
+		})
+
+		It("adds missing patterns and removes the others", func() {
+			outResponse, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(environments.CreateDeploymentBranchPolicyCallCount()).Should(Equal(1))
+			_, env, pattern := environments.CreateDeploymentBranchPolicyArgsForCall(0)
+			Ω(env).Should(Equal("pr-123"))
+			Ω(pattern).Should(Equal("release/*"))
+
+			Ω(environments.DeleteDeploymentBranchPolicyCallCount()).Should(Equal(1))
+			_, env, id := environments.DeleteDeploymentBranchPolicyArgsForCall(0)
+			Ω(env).Should(Equal("pr-123"))
+			Ω(id).Should(Equal(int64(2)))
+
+			Ω(outResponse.Metadata).Should(ContainElement(
+				resource.MetadataPair{Name: "deployment_branches", Value: "main,release/*"},
+			))
+		})
+	})
+
+	Context("when delete is set", func() {
+		BeforeEach(func() {
+			request.Params.Delete = true
+		})
+
+		It("deletes the environment", func() {
+			outResponse, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(environments.CreateOrUpdateEnvironmentCallCount()).Should(Equal(0))
+			Ω(environments.DeleteEnvironmentCallCount()).Should(Equal(1))
+			_, name := environments.DeleteEnvironmentArgsForCall(0)
+			Ω(name).Should(Equal("pr-123"))
+
+			Ω(outResponse.Version).Should(Equal(resource.Version{Environment: "pr-123", EnvironmentID: "99", Deleted: "true"}))
+			Ω(outResponse.Metadata).Should(ContainElement(
+				resource.MetadataPair{Name: "deleted", Value: "true"},
+			))
+		})
+
+		It("does not delete an environment it cannot find", func() {
+			environments.GetEnvironmentReturns(nil, errors.New("not found"))
+
+			_, err := command.Run(context.Background(), sourcesDir, request)
+			Ω(err).Should(MatchError("not found"))
+			Ω(environments.DeleteEnvironmentCallCount()).Should(Equal(0))
+		})
+	})
+
+	It("requires an environment", func() {
+		request.Params.Environment = nil
+
+		_, err := command.Run(context.Background(), sourcesDir, request)
+		Ω(err).Should(MatchError("environment is a required parameter"))
+	})
+})
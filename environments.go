@@ -0,0 +1,246 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-github/v28/github"
+)
+
+// Environment is a GitHub environment. go-github does not support the
+// environments API yet, so these types follow the API responses.
+type Environment struct {
+	ID                     *int64                `json:"id,omitempty"`
+	Name                   *string               `json:"name,omitempty"`
+	URL                    *string               `json:"url,omitempty"`
+	HTMLURL                *string               `json:"html_url,omitempty"`
+	CreatedAt              *github.Timestamp     `json:"created_at,omitempty"`
+	UpdatedAt              *github.Timestamp     `json:"updated_at,omitempty"`
+	ProtectionRules        []*ProtectionRule     `json:"protection_rules,omitempty"`
+	DeploymentBranchPolicy *BranchPolicySettings `json:"deployment_branch_policy,omitempty"`
+}
+
+// ProtectionRule is one of the rules that must pass before a deployment to
+// an environment can proceed. Its Type is one of "wait_timer",
+// "required_reviewers" or "branch_policy".
+type ProtectionRule struct {
+	ID        *int64              `json:"id,omitempty"`
+	Type      *string             `json:"type,omitempty"`
+	WaitTimer *int                `json:"wait_timer,omitempty"`
+	Reviewers []*RequiredReviewer `json:"reviewers,omitempty"`
+}
+
+// RequiredReviewer is a user or team that can approve deployments to an
+// environment.
+type RequiredReviewer struct {
+	Type     *string   `json:"type,omitempty"`
+	Reviewer *Reviewer `json:"reviewer,omitempty"`
+}
+
+// Reviewer is the user or team in a RequiredReviewer. Users have a Login and
+// teams have a Slug.
+type Reviewer struct {
+	ID    *int64  `json:"id,omitempty"`
+	Login *string `json:"login,omitempty"`
+	Slug  *string `json:"slug,omitempty"`
+}
+
+// BranchPolicySettings restricts which branches can deploy to an
+// environment, either to protected branches or to branches matching the
+// environment's deployment branch policies.
+type BranchPolicySettings struct {
+	ProtectedBranches    bool `json:"protected_branches"`
+	CustomBranchPolicies bool `json:"custom_branch_policies"`
+}
+
+// DeploymentBranchPolicy is a branch name pattern that is allowed to deploy
+// to an environment with custom branch policies.
+type DeploymentBranchPolicy struct {
+	ID   *int64  `json:"id,omitempty"`
+	Name *string `json:"name,omitempty"`
+}
+
+// EnvironmentRequest creates or replaces the protection rules of an
+// environment. Reviewers and DeploymentBranchPolicy are always sent, so that
+// leaving them unset removes the reviewers and allows every branch to deploy.
+type EnvironmentRequest struct {
+	WaitTimer              *int                   `json:"wait_timer,omitempty"`
+	Reviewers              []*EnvironmentReviewer `json:"reviewers"`
+	DeploymentBranchPolicy *BranchPolicySettings  `json:"deployment_branch_policy"`
+}
+
+// EnvironmentReviewer is a user or team, by ID, that is required to review
+// deployments to an environment. Type is either "User" or "Team".
+type EnvironmentReviewer struct {
+	Type *string `json:"type"`
+	ID   *int64  `json:"id"`
+}
+
+func (e *Environment) GetID() int64 {
+	if e == nil || e.ID == nil {
+		return 0
+	}
+	return *e.ID
+}
+
+func (e *Environment) GetName() string {
+	if e == nil || e.Name == nil {
+		return ""
+	}
+	return *e.Name
+}
+
+func (p *DeploymentBranchPolicy) GetID() int64 {
+	if p == nil || p.ID == nil {
+		return 0
+	}
+	return *p.ID
+}
+
+func (p *DeploymentBranchPolicy) GetName() string {
+	if p == nil || p.Name == nil {
+		return ""
+	}
+	return *p.Name
+}
+
+// GetName returns the login of a user or the slug of a team.
+func (r *RequiredReviewer) GetName() string {
+	if r == nil || r.Reviewer == nil {
+		return ""
+	}
+	if r.Reviewer.Login != nil {
+		return *r.Reviewer.Login
+	}
+	if r.Reviewer.Slug != nil {
+		return *r.Reviewer.Slug
+	}
+	return ""
+}
+
+func (g *GitHubClient) GetEnvironment(ctx context.Context, name string) (*Environment, error) {
+	req, err := g.client.NewRequest("GET", g.environmentURL(name), nil)
+	if err != nil {
+		return &Environment{}, err
+	}
+
+	environment := &Environment{}
+	res, err := g.client.Do(ctx, req, environment)
+	if err != nil {
+		return &Environment{}, err
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return environment, nil
+}
+
+func (g *GitHubClient) CreateOrUpdateEnvironment(ctx context.Context, name string, request *EnvironmentRequest) (*Environment, error) {
+	req, err := g.client.NewRequest("PUT", g.environmentURL(name), request)
+	if err != nil {
+		return &Environment{}, err
+	}
+
+	environment := &Environment{}
+	res, err := g.client.Do(ctx, req, environment)
+	if err != nil {
+		return &Environment{}, err
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return environment, nil
+}
+
+func (g *GitHubClient) DeleteEnvironment(ctx context.Context, name string) error {
+	req, err := g.client.NewRequest("DELETE", g.environmentURL(name), nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := g.client.Do(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+func (g *GitHubClient) ListDeploymentBranchPolicies(ctx context.Context, environment string) ([]*DeploymentBranchPolicy, error) {
+	allPolicies := []*DeploymentBranchPolicy{}
+	for page := 1; ; page++ {
+		u := fmt.Sprintf("%s/deployment-branch-policies?per_page=%d&page=%d", g.environmentURL(environment), g.pageSize, page)
+		req, err := g.client.NewRequest("GET", u, nil)
+		if err != nil {
+			return []*DeploymentBranchPolicy{}, err
+		}
+
+		var policies struct {
+			BranchPolicies []*DeploymentBranchPolicy `json:"branch_policies"`
+		}
+		res, err := g.client.Do(ctx, req, &policies)
+		if err != nil {
+			return []*DeploymentBranchPolicy{}, err
+		}
+
+		err = res.Body.Close()
+		if err != nil {
+			return []*DeploymentBranchPolicy{}, err
+		}
+
+		allPolicies = append(allPolicies, policies.BranchPolicies...)
+
+		if res.NextPage == 0 || g.reachedMaxPages(page) {
+			break
+		}
+	}
+
+	return allPolicies, nil
+}
+
+func (g *GitHubClient) CreateDeploymentBranchPolicy(ctx context.Context, environment string, name string) (*DeploymentBranchPolicy, error) {
+	u := fmt.Sprintf("%s/deployment-branch-policies", g.environmentURL(environment))
+	req, err := g.client.NewRequest("POST", u, &DeploymentBranchPolicy{Name: &name})
+	if err != nil {
+		return &DeploymentBranchPolicy{}, err
+	}
+
+	policy := &DeploymentBranchPolicy{}
+	res, err := g.client.Do(ctx, req, policy)
+	if err != nil {
+		return &DeploymentBranchPolicy{}, err
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return policy, nil
+}
+
+func (g *GitHubClient) DeleteDeploymentBranchPolicy(ctx context.Context, environment string, ID int64) error {
+	u := fmt.Sprintf("%s/deployment-branch-policies/%v", g.environmentURL(environment), ID)
+	req, err := g.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := g.client.Do(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+func (g *GitHubClient) environmentURL(name string) string {
+	return fmt.Sprintf("repos/%v/%v/environments/%v", g.user, g.repository, url.PathEscape(name))
+}
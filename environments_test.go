@@ -0,0 +1,98 @@
+package resource_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Environments", func() {
+	var (
+		server *httptest.Server
+		client *resource.GitHubClient
+
+		method, path, body string
+		response           string
+	)
+
+	BeforeEach(func() {
+		response = `{}`
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			method, path = r.Method, r.URL.EscapedPath()
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			fmt.Fprint(w, response)
+		}))
+
+		var err error
+		client, err = resource.NewGitHubClient(context.Background(), resource.Source{
+			User:         "owner",
+			Repository:   "repo",
+			GitHubAPIURL: server.URL + "/",
+		}, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("gets an environment and its protection rules", func() {
+		response = `{"id":1,"name":"pr/123","protection_rules":[{"type":"wait_timer","wait_timer":5},
+			{"type":"required_reviewers","reviewers":[{"type":"User","reviewer":{"id":3,"login":"theboss"}}]}],
+			"deployment_branch_policy":{"protected_branches":true,"custom_branch_policies":false}}`
+
+		environment, err := client.GetEnvironment(context.Background(), "pr/123")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(method).Should(Equal("GET"))
+		Ω(path).Should(Equal("/repos/owner/repo/environments/pr%2F123"))
+
+		Ω(environment.GetName()).Should(Equal("pr/123"))
+		Ω(environment.ProtectionRules).Should(HaveLen(2))
+		Ω(*environment.ProtectionRules[0].WaitTimer).Should(Equal(5))
+		Ω(environment.ProtectionRules[1].Reviewers[0].GetName()).Should(Equal("theboss"))
+		Ω(environment.DeploymentBranchPolicy.ProtectedBranches).Should(BeTrue())
+	})
+
+	It("allows every branch when no deployment branch policy is given", func() {
+		_, err := client.CreateOrUpdateEnvironment(context.Background(), "pr-123", &resource.EnvironmentRequest{
+			WaitTimer: github.Int(5),
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(method).Should(Equal("PUT"))
+		Ω(body).Should(MatchJSON(`{"wait_timer":5,"reviewers":null,"deployment_branch_policy":null}`))
+	})
+
+	It("deletes an environment", func() {
+		Ω(client.DeleteEnvironment(context.Background(), "pr-123")).Should(Succeed())
+		Ω(method).Should(Equal("DELETE"))
+		Ω(path).Should(Equal("/repos/owner/repo/environments/pr-123"))
+	})
+
+	It("lists deployment branch policies", func() {
+		response = `{"total_count":1,"branch_policies":[{"id":7,"name":"release/*"}]}`
+
+		policies, err := client.ListDeploymentBranchPolicies(context.Background(), "pr-123")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(path).Should(Equal("/repos/owner/repo/environments/pr-123/deployment-branch-policies"))
+		Ω(policies).Should(HaveLen(1))
+		Ω(policies[0].GetID()).Should(Equal(int64(7)))
+		Ω(policies[0].GetName()).Should(Equal("release/*"))
+	})
+
+	It("creates deployment branch policies", func() {
+		_, err := client.CreateDeploymentBranchPolicy(context.Background(), "pr-123", "release/*")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(method).Should(Equal("POST"))
+		Ω(body).Should(MatchJSON(`{"name":"release/*"}`))
+	})
+})
@@ -0,0 +1,318 @@
+// This file was generated by counterfeiter
+package fakes
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ahume/github-deployment-resource"
+)
+
+type FakeEnvironments struct {
+	GetEnvironmentStub        func(ctx context.Context, name string) (*resource.Environment, error)
+	getEnvironmentMutex       sync.RWMutex
+	getEnvironmentArgsForCall []struct {
+		ctx  context.Context
+		name string
+	}
+	getEnvironmentReturns struct {
+		result1 *resource.Environment
+		result2 error
+	}
+	CreateOrUpdateEnvironmentStub        func(ctx context.Context, name string, request *resource.EnvironmentRequest) (*resource.Environment, error)
+	createOrUpdateEnvironmentMutex       sync.RWMutex
+	createOrUpdateEnvironmentArgsForCall []struct {
+		ctx     context.Context
+		name    string
+		request *resource.EnvironmentRequest
+	}
+	createOrUpdateEnvironmentReturns struct {
+		result1 *resource.Environment
+		result2 error
+	}
+	DeleteEnvironmentStub        func(ctx context.Context, name string) error
+	deleteEnvironmentMutex       sync.RWMutex
+	deleteEnvironmentArgsForCall []struct {
+		ctx  context.Context
+		name string
+	}
+	deleteEnvironmentReturns struct {
+		result1 error
+	}
+	ListDeploymentBranchPoliciesStub        func(ctx context.Context, environment string) ([]*resource.DeploymentBranchPolicy, error)
+	listDeploymentBranchPoliciesMutex       sync.RWMutex
+	listDeploymentBranchPoliciesArgsForCall []struct {
+		ctx         context.Context
+		environment string
+	}
+	listDeploymentBranchPoliciesReturns struct {
+		result1 []*resource.DeploymentBranchPolicy
+		result2 error
+	}
+	CreateDeploymentBranchPolicyStub        func(ctx context.Context, environment string, name string) (*resource.DeploymentBranchPolicy, error)
+	createDeploymentBranchPolicyMutex       sync.RWMutex
+	createDeploymentBranchPolicyArgsForCall []struct {
+		ctx         context.Context
+		environment string
+		name        string
+	}
+	createDeploymentBranchPolicyReturns struct {
+		result1 *resource.DeploymentBranchPolicy
+		result2 error
+	}
+	DeleteDeploymentBranchPolicyStub        func(ctx context.Context, environment string, ID int64) error
+	deleteDeploymentBranchPolicyMutex       sync.RWMutex
+	deleteDeploymentBranchPolicyArgsForCall []struct {
+		ctx         context.Context
+		environment string
+		ID          int64
+	}
+	deleteDeploymentBranchPolicyReturns struct {
+		result1 error
+	}
+	invocations      map[string][][]interface{}
+	invocationsMutex sync.RWMutex
+}
+
+func (fake *FakeEnvironments) GetEnvironment(ctx context.Context, name string) (*resource.Environment, error) {
+	fake.getEnvironmentMutex.Lock()
+	fake.getEnvironmentArgsForCall = append(fake.getEnvironmentArgsForCall, struct {
+		ctx  context.Context
+		name string
+	}{ctx, name})
+	fake.recordInvocation("GetEnvironment", []interface{}{ctx, name})
+	fake.getEnvironmentMutex.Unlock()
+	if fake.GetEnvironmentStub != nil {
+		return fake.GetEnvironmentStub(ctx, name)
+	} else {
+		return fake.getEnvironmentReturns.result1, fake.getEnvironmentReturns.result2
+	}
+}
+
+func (fake *FakeEnvironments) GetEnvironmentCallCount() int {
+	fake.getEnvironmentMutex.RLock()
+	defer fake.getEnvironmentMutex.RUnlock()
+	return len(fake.getEnvironmentArgsForCall)
+}
+
+func (fake *FakeEnvironments) GetEnvironmentArgsForCall(i int) (context.Context, string) {
+	fake.getEnvironmentMutex.RLock()
+	defer fake.getEnvironmentMutex.RUnlock()
+	return fake.getEnvironmentArgsForCall[i].ctx, fake.getEnvironmentArgsForCall[i].name
+}
+
+func (fake *FakeEnvironments) GetEnvironmentReturns(result1 *resource.Environment, result2 error) {
+	fake.GetEnvironmentStub = nil
+	fake.getEnvironmentReturns = struct {
+		result1 *resource.Environment
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEnvironments) CreateOrUpdateEnvironment(ctx context.Context, name string, request *resource.EnvironmentRequest) (*resource.Environment, error) {
+	fake.createOrUpdateEnvironmentMutex.Lock()
+	fake.createOrUpdateEnvironmentArgsForCall = append(fake.createOrUpdateEnvironmentArgsForCall, struct {
+		ctx     context.Context
+		name    string
+		request *resource.EnvironmentRequest
+	}{ctx, name, request})
+	fake.recordInvocation("CreateOrUpdateEnvironment", []interface{}{ctx, name, request})
+	fake.createOrUpdateEnvironmentMutex.Unlock()
+	if fake.CreateOrUpdateEnvironmentStub != nil {
+		return fake.CreateOrUpdateEnvironmentStub(ctx, name, request)
+	} else {
+		return fake.createOrUpdateEnvironmentReturns.result1, fake.createOrUpdateEnvironmentReturns.result2
+	}
+}
+
+func (fake *FakeEnvironments) CreateOrUpdateEnvironmentCallCount() int {
+	fake.createOrUpdateEnvironmentMutex.RLock()
+	defer fake.createOrUpdateEnvironmentMutex.RUnlock()
+	return len(fake.createOrUpdateEnvironmentArgsForCall)
+}
+
+func (fake *FakeEnvironments) CreateOrUpdateEnvironmentArgsForCall(i int) (context.Context, string, *resource.EnvironmentRequest) {
+	fake.createOrUpdateEnvironmentMutex.RLock()
+	defer fake.createOrUpdateEnvironmentMutex.RUnlock()
+	return fake.createOrUpdateEnvironmentArgsForCall[i].ctx, fake.createOrUpdateEnvironmentArgsForCall[i].name, fake.createOrUpdateEnvironmentArgsForCall[i].request
+}
+
+func (fake *FakeEnvironments) CreateOrUpdateEnvironmentReturns(result1 *resource.Environment, result2 error) {
+	fake.CreateOrUpdateEnvironmentStub = nil
+	fake.createOrUpdateEnvironmentReturns = struct {
+		result1 *resource.Environment
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEnvironments) DeleteEnvironment(ctx context.Context, name string) error {
+	fake.deleteEnvironmentMutex.Lock()
+	fake.deleteEnvironmentArgsForCall = append(fake.deleteEnvironmentArgsForCall, struct {
+		ctx  context.Context
+		name string
+	}{ctx, name})
+	fake.recordInvocation("DeleteEnvironment", []interface{}{ctx, name})
+	fake.deleteEnvironmentMutex.Unlock()
+	if fake.DeleteEnvironmentStub != nil {
+		return fake.DeleteEnvironmentStub(ctx, name)
+	} else {
+		return fake.deleteEnvironmentReturns.result1
+	}
+}
+
+func (fake *FakeEnvironments) DeleteEnvironmentCallCount() int {
+	fake.deleteEnvironmentMutex.RLock()
+	defer fake.deleteEnvironmentMutex.RUnlock()
+	return len(fake.deleteEnvironmentArgsForCall)
+}
+
+func (fake *FakeEnvironments) DeleteEnvironmentArgsForCall(i int) (context.Context, string) {
+	fake.deleteEnvironmentMutex.RLock()
+	defer fake.deleteEnvironmentMutex.RUnlock()
+	return fake.deleteEnvironmentArgsForCall[i].ctx, fake.deleteEnvironmentArgsForCall[i].name
+}
+
+func (fake *FakeEnvironments) DeleteEnvironmentReturns(result1 error) {
+	fake.DeleteEnvironmentStub = nil
+	fake.deleteEnvironmentReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEnvironments) ListDeploymentBranchPolicies(ctx context.Context, environment string) ([]*resource.DeploymentBranchPolicy, error) {
+	fake.listDeploymentBranchPoliciesMutex.Lock()
+	fake.listDeploymentBranchPoliciesArgsForCall = append(fake.listDeploymentBranchPoliciesArgsForCall, struct {
+		ctx         context.Context
+		environment string
+	}{ctx, environment})
+	fake.recordInvocation("ListDeploymentBranchPolicies", []interface{}{ctx, environment})
+	fake.listDeploymentBranchPoliciesMutex.Unlock()
+	if fake.ListDeploymentBranchPoliciesStub != nil {
+		return fake.ListDeploymentBranchPoliciesStub(ctx, environment)
+	} else {
+		return fake.listDeploymentBranchPoliciesReturns.result1, fake.listDeploymentBranchPoliciesReturns.result2
+	}
+}
+
+func (fake *FakeEnvironments) ListDeploymentBranchPoliciesCallCount() int {
+	fake.listDeploymentBranchPoliciesMutex.RLock()
+	defer fake.listDeploymentBranchPoliciesMutex.RUnlock()
+	return len(fake.listDeploymentBranchPoliciesArgsForCall)
+}
+
+func (fake *FakeEnvironments) ListDeploymentBranchPoliciesArgsForCall(i int) (context.Context, string) {
+	fake.listDeploymentBranchPoliciesMutex.RLock()
+	defer fake.listDeploymentBranchPoliciesMutex.RUnlock()
+	return fake.listDeploymentBranchPoliciesArgsForCall[i].ctx, fake.listDeploymentBranchPoliciesArgsForCall[i].environment
+}
+
+func (fake *FakeEnvironments) ListDeploymentBranchPoliciesReturns(result1 []*resource.DeploymentBranchPolicy, result2 error) {
+	fake.ListDeploymentBranchPoliciesStub = nil
+	fake.listDeploymentBranchPoliciesReturns = struct {
+		result1 []*resource.DeploymentBranchPolicy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEnvironments) CreateDeploymentBranchPolicy(ctx context.Context, environment string, name string) (*resource.DeploymentBranchPolicy, error) {
+	fake.createDeploymentBranchPolicyMutex.Lock()
+	fake.createDeploymentBranchPolicyArgsForCall = append(fake.createDeploymentBranchPolicyArgsForCall, struct {
+		ctx         context.Context
+		environment string
+		name        string
+	}{ctx, environment, name})
+	fake.recordInvocation("CreateDeploymentBranchPolicy", []interface{}{ctx, environment, name})
+	fake.createDeploymentBranchPolicyMutex.Unlock()
+	if fake.CreateDeploymentBranchPolicyStub != nil {
+		return fake.CreateDeploymentBranchPolicyStub(ctx, environment, name)
+	} else {
+		return fake.createDeploymentBranchPolicyReturns.result1, fake.createDeploymentBranchPolicyReturns.result2
+	}
+}
+
+func (fake *FakeEnvironments) CreateDeploymentBranchPolicyCallCount() int {
+	fake.createDeploymentBranchPolicyMutex.RLock()
+	defer fake.createDeploymentBranchPolicyMutex.RUnlock()
+	return len(fake.createDeploymentBranchPolicyArgsForCall)
+}
+
+func (fake *FakeEnvironments) CreateDeploymentBranchPolicyArgsForCall(i int) (context.Context, string, string) {
+	fake.createDeploymentBranchPolicyMutex.RLock()
+	defer fake.createDeploymentBranchPolicyMutex.RUnlock()
+	return fake.createDeploymentBranchPolicyArgsForCall[i].ctx, fake.createDeploymentBranchPolicyArgsForCall[i].environment, fake.createDeploymentBranchPolicyArgsForCall[i].name
+}
+
+func (fake *FakeEnvironments) CreateDeploymentBranchPolicyReturns(result1 *resource.DeploymentBranchPolicy, result2 error) {
+	fake.CreateDeploymentBranchPolicyStub = nil
+	fake.createDeploymentBranchPolicyReturns = struct {
+		result1 *resource.DeploymentBranchPolicy
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeEnvironments) DeleteDeploymentBranchPolicy(ctx context.Context, environment string, ID int64) error {
+	fake.deleteDeploymentBranchPolicyMutex.Lock()
+	fake.deleteDeploymentBranchPolicyArgsForCall = append(fake.deleteDeploymentBranchPolicyArgsForCall, struct {
+		ctx         context.Context
+		environment string
+		ID          int64
+	}{ctx, environment, ID})
+	fake.recordInvocation("DeleteDeploymentBranchPolicy", []interface{}{ctx, environment, ID})
+	fake.deleteDeploymentBranchPolicyMutex.Unlock()
+	if fake.DeleteDeploymentBranchPolicyStub != nil {
+		return fake.DeleteDeploymentBranchPolicyStub(ctx, environment, ID)
+	} else {
+		return fake.deleteDeploymentBranchPolicyReturns.result1
+	}
+}
+
+func (fake *FakeEnvironments) DeleteDeploymentBranchPolicyCallCount() int {
+	fake.deleteDeploymentBranchPolicyMutex.RLock()
+	defer fake.deleteDeploymentBranchPolicyMutex.RUnlock()
+	return len(fake.deleteDeploymentBranchPolicyArgsForCall)
+}
+
+func (fake *FakeEnvironments) DeleteDeploymentBranchPolicyArgsForCall(i int) (context.Context, string, int64) {
+	fake.deleteDeploymentBranchPolicyMutex.RLock()
+	defer fake.deleteDeploymentBranchPolicyMutex.RUnlock()
+	return fake.deleteDeploymentBranchPolicyArgsForCall[i].ctx, fake.deleteDeploymentBranchPolicyArgsForCall[i].environment, fake.deleteDeploymentBranchPolicyArgsForCall[i].ID
+}
+
+func (fake *FakeEnvironments) DeleteDeploymentBranchPolicyReturns(result1 error) {
+	fake.DeleteDeploymentBranchPolicyStub = nil
+	fake.deleteDeploymentBranchPolicyReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeEnvironments) Invocations() map[string][][]interface{} {
+	fake.invocationsMutex.RLock()
+	defer fake.invocationsMutex.RUnlock()
+	fake.getEnvironmentMutex.RLock()
+	defer fake.getEnvironmentMutex.RUnlock()
+	fake.createOrUpdateEnvironmentMutex.RLock()
+	defer fake.createOrUpdateEnvironmentMutex.RUnlock()
+	fake.deleteEnvironmentMutex.RLock()
+	defer fake.deleteEnvironmentMutex.RUnlock()
+	fake.listDeploymentBranchPoliciesMutex.RLock()
+	defer fake.listDeploymentBranchPoliciesMutex.RUnlock()
+	fake.createDeploymentBranchPolicyMutex.RLock()
+	defer fake.createDeploymentBranchPolicyMutex.RUnlock()
+	fake.deleteDeploymentBranchPolicyMutex.RLock()
+	defer fake.deleteDeploymentBranchPolicyMutex.RUnlock()
+	return fake.invocations
+}
+
+func (fake *FakeEnvironments) recordInvocation(key string, args []interface{}) {
+	fake.invocationsMutex.Lock()
+	defer fake.invocationsMutex.Unlock()
+	if fake.invocations == nil {
+		fake.invocations = map[string][][]interface{}{}
+	}
+	if fake.invocations[key] == nil {
+		fake.invocations[key] = [][]interface{}{}
+	}
+	fake.invocations[key] = append(fake.invocations[key], args)
+}
+
+var _ resource.Environments = new(FakeEnvironments)
@@ -2,6 +2,7 @@
 package fakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/ahume/github-deployment-resource"
@@ -9,61 +10,90 @@ import (
 )
 
 type FakeGitHub struct {
-	ListDeploymentsStub        func() ([]*github.Deployment, error)
+	ListDeploymentsStub        func(ctx context.Context, opts resource.ListDeploymentsOptions) ([]*resource.Deployment, error)
 	listDeploymentsMutex       sync.RWMutex
-	listDeploymentsArgsForCall []struct{}
-	listDeploymentsReturns     struct {
-		result1 []*github.Deployment
+	listDeploymentsArgsForCall []struct {
+		ctx  context.Context
+		opts resource.ListDeploymentsOptions
+	}
+	listDeploymentsReturns struct {
+		result1 []*resource.Deployment
 		result2 error
 	}
-	ListDeploymentStatusesStub        func(ID int64) ([]*github.DeploymentStatus, error)
+	ListDeploymentStatusesStub        func(ctx context.Context, ID int64) ([]*github.DeploymentStatus, error)
 	listDeploymentStatusesMutex       sync.RWMutex
 	listDeploymentStatusesArgsForCall []struct {
-		ID int64
+		ctx context.Context
+		ID  int64
 	}
 	listDeploymentStatusesReturns struct {
 		result1 []*github.DeploymentStatus
 		result2 error
 	}
-	GetDeploymentStub        func(ID int64) (*github.Deployment, error)
+	GetLatestDeploymentStatusStub        func(ctx context.Context, ID int64) (*github.DeploymentStatus, error)
+	getLatestDeploymentStatusMutex       sync.RWMutex
+	getLatestDeploymentStatusArgsForCall []struct {
+		ctx context.Context
+		ID  int64
+	}
+	getLatestDeploymentStatusReturns struct {
+		result1 *github.DeploymentStatus
+		result2 error
+	}
+	GetDeploymentStub        func(ctx context.Context, ID int64) (*resource.Deployment, error)
 	getDeploymentMutex       sync.RWMutex
 	getDeploymentArgsForCall []struct {
-		ID int64
+		ctx context.Context
+		ID  int64
 	}
 	getDeploymentReturns struct {
-		result1 *github.Deployment
+		result1 *resource.Deployment
 		result2 error
 	}
-	CreateDeploymentStub        func(request *github.DeploymentRequest) (*github.Deployment, error)
+	CreateDeploymentStub        func(ctx context.Context, request *github.DeploymentRequest) (*resource.Deployment, error)
 	createDeploymentMutex       sync.RWMutex
 	createDeploymentArgsForCall []struct {
+		ctx     context.Context
 		request *github.DeploymentRequest
 	}
 	createDeploymentReturns struct {
-		result1 *github.Deployment
+		result1 *resource.Deployment
 		result2 error
 	}
-	CreateDeploymentStatusStub        func(ID int64, request *github.DeploymentStatusRequest) (*github.DeploymentStatus, error)
+	CreateDeploymentStatusStub        func(ctx context.Context, ID int64, request *resource.DeploymentStatusRequest) (*github.DeploymentStatus, error)
 	createDeploymentStatusMutex       sync.RWMutex
 	createDeploymentStatusArgsForCall []struct {
+		ctx     context.Context
 		ID      int64
-		request *github.DeploymentStatusRequest
+		request *resource.DeploymentStatusRequest
 	}
 	createDeploymentStatusReturns struct {
 		result1 *github.DeploymentStatus
 		result2 error
 	}
+	DeleteDeploymentStub        func(ctx context.Context, ID int64) error
+	deleteDeploymentMutex       sync.RWMutex
+	deleteDeploymentArgsForCall []struct {
+		ctx context.Context
+		ID  int64
+	}
+	deleteDeploymentReturns struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeGitHub) ListDeployments() ([]*github.Deployment, error) {
+func (fake *FakeGitHub) ListDeployments(ctx context.Context, opts resource.ListDeploymentsOptions) ([]*resource.Deployment, error) {
 	fake.listDeploymentsMutex.Lock()
-	fake.listDeploymentsArgsForCall = append(fake.listDeploymentsArgsForCall, struct{}{})
-	fake.recordInvocation("ListDeployments", []interface{}{})
+	fake.listDeploymentsArgsForCall = append(fake.listDeploymentsArgsForCall, struct {
+		ctx  context.Context
+		opts resource.ListDeploymentsOptions
+	}{ctx, opts})
+	fake.recordInvocation("ListDeployments", []interface{}{ctx, opts})
 	fake.listDeploymentsMutex.Unlock()
 	if fake.ListDeploymentsStub != nil {
-		return fake.ListDeploymentsStub()
+		return fake.ListDeploymentsStub(ctx, opts)
 	} else {
 		return fake.listDeploymentsReturns.result1, fake.listDeploymentsReturns.result2
 	}
@@ -75,23 +105,30 @@ func (fake *FakeGitHub) ListDeploymentsCallCount() int {
 	return len(fake.listDeploymentsArgsForCall)
 }
 
-func (fake *FakeGitHub) ListDeploymentsReturns(result1 []*github.Deployment, result2 error) {
+func (fake *FakeGitHub) ListDeploymentsArgsForCall(i int) (context.Context, resource.ListDeploymentsOptions) {
+	fake.listDeploymentsMutex.RLock()
+	defer fake.listDeploymentsMutex.RUnlock()
+	return fake.listDeploymentsArgsForCall[i].ctx, fake.listDeploymentsArgsForCall[i].opts
+}
+
+func (fake *FakeGitHub) ListDeploymentsReturns(result1 []*resource.Deployment, result2 error) {
 	fake.ListDeploymentsStub = nil
 	fake.listDeploymentsReturns = struct {
-		result1 []*github.Deployment
+		result1 []*resource.Deployment
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeGitHub) ListDeploymentStatuses(ID int64) ([]*github.DeploymentStatus, error) {
+func (fake *FakeGitHub) ListDeploymentStatuses(ctx context.Context, ID int64) ([]*github.DeploymentStatus, error) {
 	fake.listDeploymentStatusesMutex.Lock()
 	fake.listDeploymentStatusesArgsForCall = append(fake.listDeploymentStatusesArgsForCall, struct {
-		ID int64
-	}{ID})
-	fake.recordInvocation("ListDeploymentStatuses", []interface{}{ID})
+		ctx context.Context
+		ID  int64
+	}{ctx, ID})
+	fake.recordInvocation("ListDeploymentStatuses", []interface{}{ctx, ID})
 	fake.listDeploymentStatusesMutex.Unlock()
 	if fake.ListDeploymentStatusesStub != nil {
-		return fake.ListDeploymentStatusesStub(ID)
+		return fake.ListDeploymentStatusesStub(ctx, ID)
 	} else {
 		return fake.listDeploymentStatusesReturns.result1, fake.listDeploymentStatusesReturns.result2
 	}
@@ -103,10 +140,10 @@ func (fake *FakeGitHub) ListDeploymentStatusesCallCount() int {
 	return len(fake.listDeploymentStatusesArgsForCall)
 }
 
-func (fake *FakeGitHub) ListDeploymentStatusesArgsForCall(i int) int64 {
+func (fake *FakeGitHub) ListDeploymentStatusesArgsForCall(i int) (context.Context, int64) {
 	fake.listDeploymentStatusesMutex.RLock()
 	defer fake.listDeploymentStatusesMutex.RUnlock()
-	return fake.listDeploymentStatusesArgsForCall[i].ID
+	return fake.listDeploymentStatusesArgsForCall[i].ctx, fake.listDeploymentStatusesArgsForCall[i].ID
 }
 
 func (fake *FakeGitHub) ListDeploymentStatusesReturns(result1 []*github.DeploymentStatus, result2 error) {
@@ -117,15 +154,51 @@ func (fake *FakeGitHub) ListDeploymentStatusesReturns(result1 []*github.Deployme
 	}{result1, result2}
 }
 
-func (fake *FakeGitHub) GetDeployment(ID int64) (*github.Deployment, error) {
+func (fake *FakeGitHub) GetLatestDeploymentStatus(ctx context.Context, ID int64) (*github.DeploymentStatus, error) {
+	fake.getLatestDeploymentStatusMutex.Lock()
+	fake.getLatestDeploymentStatusArgsForCall = append(fake.getLatestDeploymentStatusArgsForCall, struct {
+		ctx context.Context
+		ID  int64
+	}{ctx, ID})
+	fake.recordInvocation("GetLatestDeploymentStatus", []interface{}{ctx, ID})
+	fake.getLatestDeploymentStatusMutex.Unlock()
+	if fake.GetLatestDeploymentStatusStub != nil {
+		return fake.GetLatestDeploymentStatusStub(ctx, ID)
+	} else {
+		return fake.getLatestDeploymentStatusReturns.result1, fake.getLatestDeploymentStatusReturns.result2
+	}
+}
+
+func (fake *FakeGitHub) GetLatestDeploymentStatusCallCount() int {
+	fake.getLatestDeploymentStatusMutex.RLock()
+	defer fake.getLatestDeploymentStatusMutex.RUnlock()
+	return len(fake.getLatestDeploymentStatusArgsForCall)
+}
+
+func (fake *FakeGitHub) GetLatestDeploymentStatusArgsForCall(i int) (context.Context, int64) {
+	fake.getLatestDeploymentStatusMutex.RLock()
+	defer fake.getLatestDeploymentStatusMutex.RUnlock()
+	return fake.getLatestDeploymentStatusArgsForCall[i].ctx, fake.getLatestDeploymentStatusArgsForCall[i].ID
+}
+
+func (fake *FakeGitHub) GetLatestDeploymentStatusReturns(result1 *github.DeploymentStatus, result2 error) {
+	fake.GetLatestDeploymentStatusStub = nil
+	fake.getLatestDeploymentStatusReturns = struct {
+		result1 *github.DeploymentStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeGitHub) GetDeployment(ctx context.Context, ID int64) (*resource.Deployment, error) {
 	fake.getDeploymentMutex.Lock()
 	fake.getDeploymentArgsForCall = append(fake.getDeploymentArgsForCall, struct {
-		ID int64
-	}{ID})
-	fake.recordInvocation("GetDeployment", []interface{}{ID})
+		ctx context.Context
+		ID  int64
+	}{ctx, ID})
+	fake.recordInvocation("GetDeployment", []interface{}{ctx, ID})
 	fake.getDeploymentMutex.Unlock()
 	if fake.GetDeploymentStub != nil {
-		return fake.GetDeploymentStub(ID)
+		return fake.GetDeploymentStub(ctx, ID)
 	} else {
 		return fake.getDeploymentReturns.result1, fake.getDeploymentReturns.result2
 	}
@@ -137,29 +210,30 @@ func (fake *FakeGitHub) GetDeploymentCallCount() int {
 	return len(fake.getDeploymentArgsForCall)
 }
 
-func (fake *FakeGitHub) GetDeploymentArgsForCall(i int) int64 {
+func (fake *FakeGitHub) GetDeploymentArgsForCall(i int) (context.Context, int64) {
 	fake.getDeploymentMutex.RLock()
 	defer fake.getDeploymentMutex.RUnlock()
-	return fake.getDeploymentArgsForCall[i].ID
+	return fake.getDeploymentArgsForCall[i].ctx, fake.getDeploymentArgsForCall[i].ID
 }
 
-func (fake *FakeGitHub) GetDeploymentReturns(result1 *github.Deployment, result2 error) {
+func (fake *FakeGitHub) GetDeploymentReturns(result1 *resource.Deployment, result2 error) {
 	fake.GetDeploymentStub = nil
 	fake.getDeploymentReturns = struct {
-		result1 *github.Deployment
+		result1 *resource.Deployment
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeGitHub) CreateDeployment(request *github.DeploymentRequest) (*github.Deployment, error) {
+func (fake *FakeGitHub) CreateDeployment(ctx context.Context, request *github.DeploymentRequest) (*resource.Deployment, error) {
 	fake.createDeploymentMutex.Lock()
 	fake.createDeploymentArgsForCall = append(fake.createDeploymentArgsForCall, struct {
+		ctx     context.Context
 		request *github.DeploymentRequest
-	}{request})
-	fake.recordInvocation("CreateDeployment", []interface{}{request})
+	}{ctx, request})
+	fake.recordInvocation("CreateDeployment", []interface{}{ctx, request})
 	fake.createDeploymentMutex.Unlock()
 	if fake.CreateDeploymentStub != nil {
-		return fake.CreateDeploymentStub(request)
+		return fake.CreateDeploymentStub(ctx, request)
 	} else {
 		return fake.createDeploymentReturns.result1, fake.createDeploymentReturns.result2
 	}
@@ -171,30 +245,31 @@ func (fake *FakeGitHub) CreateDeploymentCallCount() int {
 	return len(fake.createDeploymentArgsForCall)
 }
 
-func (fake *FakeGitHub) CreateDeploymentArgsForCall(i int) *github.DeploymentRequest {
+func (fake *FakeGitHub) CreateDeploymentArgsForCall(i int) (context.Context, *github.DeploymentRequest) {
 	fake.createDeploymentMutex.RLock()
 	defer fake.createDeploymentMutex.RUnlock()
-	return fake.createDeploymentArgsForCall[i].request
+	return fake.createDeploymentArgsForCall[i].ctx, fake.createDeploymentArgsForCall[i].request
 }
 
-func (fake *FakeGitHub) CreateDeploymentReturns(result1 *github.Deployment, result2 error) {
+func (fake *FakeGitHub) CreateDeploymentReturns(result1 *resource.Deployment, result2 error) {
 	fake.CreateDeploymentStub = nil
 	fake.createDeploymentReturns = struct {
-		result1 *github.Deployment
+		result1 *resource.Deployment
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeGitHub) CreateDeploymentStatus(ID int64, request *github.DeploymentStatusRequest) (*github.DeploymentStatus, error) {
+func (fake *FakeGitHub) CreateDeploymentStatus(ctx context.Context, ID int64, request *resource.DeploymentStatusRequest) (*github.DeploymentStatus, error) {
 	fake.createDeploymentStatusMutex.Lock()
 	fake.createDeploymentStatusArgsForCall = append(fake.createDeploymentStatusArgsForCall, struct {
+		ctx     context.Context
 		ID      int64
-		request *github.DeploymentStatusRequest
-	}{ID, request})
-	fake.recordInvocation("CreateDeploymentStatus", []interface{}{ID, request})
+		request *resource.DeploymentStatusRequest
+	}{ctx, ID, request})
+	fake.recordInvocation("CreateDeploymentStatus", []interface{}{ctx, ID, request})
 	fake.createDeploymentStatusMutex.Unlock()
 	if fake.CreateDeploymentStatusStub != nil {
-		return fake.CreateDeploymentStatusStub(ID, request)
+		return fake.CreateDeploymentStatusStub(ctx, ID, request)
 	} else {
 		return fake.createDeploymentStatusReturns.result1, fake.createDeploymentStatusReturns.result2
 	}
@@ -206,10 +281,10 @@ func (fake *FakeGitHub) CreateDeploymentStatusCallCount() int {
 	return len(fake.createDeploymentStatusArgsForCall)
 }
 
-func (fake *FakeGitHub) CreateDeploymentStatusArgsForCall(i int) (int64, *github.DeploymentStatusRequest) {
+func (fake *FakeGitHub) CreateDeploymentStatusArgsForCall(i int) (context.Context, int64, *resource.DeploymentStatusRequest) {
 	fake.createDeploymentStatusMutex.RLock()
 	defer fake.createDeploymentStatusMutex.RUnlock()
-	return fake.createDeploymentStatusArgsForCall[i].ID, fake.createDeploymentStatusArgsForCall[i].request
+	return fake.createDeploymentStatusArgsForCall[i].ctx, fake.createDeploymentStatusArgsForCall[i].ID, fake.createDeploymentStatusArgsForCall[i].request
 }
 
 func (fake *FakeGitHub) CreateDeploymentStatusReturns(result1 *github.DeploymentStatus, result2 error) {
@@ -220,6 +295,40 @@ func (fake *FakeGitHub) CreateDeploymentStatusReturns(result1 *github.Deployment
 	}{result1, result2}
 }
 
+func (fake *FakeGitHub) DeleteDeployment(ctx context.Context, ID int64) error {
+	fake.deleteDeploymentMutex.Lock()
+	fake.deleteDeploymentArgsForCall = append(fake.deleteDeploymentArgsForCall, struct {
+		ctx context.Context
+		ID  int64
+	}{ctx, ID})
+	fake.recordInvocation("DeleteDeployment", []interface{}{ctx, ID})
+	fake.deleteDeploymentMutex.Unlock()
+	if fake.DeleteDeploymentStub != nil {
+		return fake.DeleteDeploymentStub(ctx, ID)
+	} else {
+		return fake.deleteDeploymentReturns.result1
+	}
+}
+
+func (fake *FakeGitHub) DeleteDeploymentCallCount() int {
+	fake.deleteDeploymentMutex.RLock()
+	defer fake.deleteDeploymentMutex.RUnlock()
+	return len(fake.deleteDeploymentArgsForCall)
+}
+
+func (fake *FakeGitHub) DeleteDeploymentArgsForCall(i int) (context.Context, int64) {
+	fake.deleteDeploymentMutex.RLock()
+	defer fake.deleteDeploymentMutex.RUnlock()
+	return fake.deleteDeploymentArgsForCall[i].ctx, fake.deleteDeploymentArgsForCall[i].ID
+}
+
+func (fake *FakeGitHub) DeleteDeploymentReturns(result1 error) {
+	fake.DeleteDeploymentStub = nil
+	fake.deleteDeploymentReturns = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeGitHub) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
@@ -227,12 +336,16 @@ func (fake *FakeGitHub) Invocations() map[string][][]interface{} {
 	defer fake.listDeploymentsMutex.RUnlock()
 	fake.listDeploymentStatusesMutex.RLock()
 	defer fake.listDeploymentStatusesMutex.RUnlock()
+	fake.getLatestDeploymentStatusMutex.RLock()
+	defer fake.getLatestDeploymentStatusMutex.RUnlock()
 	fake.getDeploymentMutex.RLock()
 	defer fake.getDeploymentMutex.RUnlock()
 	fake.createDeploymentMutex.RLock()
 	defer fake.createDeploymentMutex.RUnlock()
 	fake.createDeploymentStatusMutex.RLock()
 	defer fake.createDeploymentStatusMutex.RUnlock()
+	fake.deleteDeploymentMutex.RLock()
+	defer fake.deleteDeploymentMutex.RUnlock()
 	return fake.invocations
 }
 
@@ -0,0 +1,279 @@
+package resource
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Filter includes or excludes deployments by matching one of their fields
+// against patterns. Patterns are globs, where * matches any run of characters
+// and ? matches a single character, unless they are wrapped in slashes, in
+// which case they are regular expressions. For example "review-*" and
+// "/^review-[0-9]+$/".
+type Filter struct {
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+}
+
+type compiledFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// matches reports whether value matches at least one include pattern, if
+// there are any, and none of the exclude patterns.
+func (f *compiledFilter) matches(value string) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.include) > 0 && !matchesAny(f.include, value) {
+		return false
+	}
+
+	return !matchesAny(f.exclude, value)
+}
+
+func matchesAny(patterns []*regexp.Regexp, value string) bool {
+	for _, pattern := range patterns {
+		if pattern.MatchString(value) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileFilter(name string, filter *Filter) (*compiledFilter, error) {
+	if filter == nil {
+		return nil, nil
+	}
+
+	compiled := &compiledFilter{}
+	for _, pattern := range filter.Include {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s include pattern %q: %s", name, pattern, err)
+		}
+		compiled.include = append(compiled.include, re)
+	}
+
+	for _, pattern := range filter.Exclude {
+		re, err := compilePattern(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s exclude pattern %q: %s", name, pattern, err)
+		}
+		compiled.exclude = append(compiled.exclude, re)
+	}
+
+	return compiled, nil
+}
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) >= 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		return regexp.Compile(pattern[1 : len(pattern)-1])
+	}
+
+	var expr strings.Builder
+	expr.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	expr.WriteString("$")
+
+	return regexp.Compile(expr.String())
+}
+
+// PayloadFilter matches a value in the JSON payload of a deployment. Path is
+// a dot separated path into the payload. Only one of Equals, Matches or
+// Exists should be set.
+type PayloadFilter struct {
+	Path    string      `json:"path"`
+	Equals  interface{} `json:"equals"`
+	Matches string      `json:"matches"`
+	Exists  *bool       `json:"exists"`
+}
+
+type compiledPayloadFilter struct {
+	path    string
+	equals  interface{}
+	pattern *regexp.Regexp
+	exists  *bool
+}
+
+func compilePayloadFilter(filter PayloadFilter) (*compiledPayloadFilter, error) {
+	if filter.Path == "" {
+		return nil, errors.New("payload_filter entries require a path")
+	}
+
+	set := 0
+	for _, isSet := range []bool{filter.Equals != nil, filter.Matches != "", filter.Exists != nil} {
+		if isSet {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, fmt.Errorf("payload_filter for %q must set exactly one of equals, matches or exists", filter.Path)
+	}
+
+	compiled := &compiledPayloadFilter{
+		path:   filter.Path,
+		equals: filter.Equals,
+		exists: filter.Exists,
+	}
+
+	if filter.Matches != "" {
+		re, err := regexp.Compile(filter.Matches)
+		if err != nil {
+			return nil, fmt.Errorf("invalid payload_filter pattern %q for %q: %s", filter.Matches, filter.Path, err)
+		}
+		compiled.pattern = re
+	}
+
+	return compiled, nil
+}
+
+func (f *compiledPayloadFilter) matches(payload interface{}) bool {
+	value, found := lookupPath(payload, f.path)
+
+	switch {
+	case f.exists != nil:
+		return found == *f.exists
+	case !found:
+		return false
+	case f.pattern != nil:
+		s, ok := value.(string)
+		if !ok {
+			s = fmt.Sprint(value)
+		}
+		return f.pattern.MatchString(s)
+	default:
+		return reflect.DeepEqual(value, f.equals)
+	}
+}
+
+// decodePayload decodes the payload of a deployment. GitHub returns payloads
+// created from a JSON string as that string, so those are decoded again.
+func decodePayload(deployment *Deployment) interface{} {
+	var payload interface{}
+	if err := json.Unmarshal(deployment.Payload, &payload); err != nil {
+		return nil
+	}
+
+	if s, ok := payload.(string); ok {
+		var inner interface{}
+		if err := json.Unmarshal([]byte(s), &inner); err == nil {
+			return inner
+		}
+	}
+
+	return payload
+}
+
+// ownPipelineFilters matches the concourse_payload that DeploymentOutCommand
+// adds to each deployment against the pipeline that is running check.
+// Concourse does not give check build metadata, so the pipeline and team
+// names must be set in the source.
+func ownPipelineFilters(source Source) ([]*compiledPayloadFilter, error) {
+	if source.PipelineName == "" || source.TeamName == "" {
+		return nil, errors.New("only_own_pipeline requires pipeline_name and team_name to be set in the source")
+	}
+
+	return []*compiledPayloadFilter{
+		{path: "concourse_payload.build_pipeline_name", equals: source.PipelineName},
+		{path: "concourse_payload.build_team_name", equals: source.TeamName},
+	}, nil
+}
+
+// deploymentFilters holds the compiled source filters that check applies to
+// each deployment.
+type deploymentFilters struct {
+	ref         *compiledFilter
+	task        *compiledFilter
+	environment *compiledFilter
+	creator     *compiledFilter
+	payload     []*compiledPayloadFilter
+
+	productionOnly   bool
+	excludeTransient bool
+}
+
+func newDeploymentFilters(source Source) (*deploymentFilters, error) {
+	var (
+		filters = deploymentFilters{
+			productionOnly:   source.ProductionOnly,
+			excludeTransient: source.ExcludeTransient,
+		}
+		err error
+	)
+
+	if filters.ref, err = compileFilter("ref_filter", source.RefFilter); err != nil {
+		return nil, err
+	}
+	if filters.task, err = compileFilter("task_filter", source.TaskFilter); err != nil {
+		return nil, err
+	}
+	if filters.environment, err = compileFilter("environment_filter", source.EnvironmentFilter); err != nil {
+		return nil, err
+	}
+	if filters.creator, err = compileFilter("creator_filter", source.CreatorFilter); err != nil {
+		return nil, err
+	}
+
+	for _, payloadFilter := range source.PayloadFilter {
+		compiled, err := compilePayloadFilter(payloadFilter)
+		if err != nil {
+			return nil, err
+		}
+		filters.payload = append(filters.payload, compiled)
+	}
+
+	if source.OnlyOwnPipeline {
+		own, err := ownPipelineFilters(source)
+		if err != nil {
+			return nil, err
+		}
+		filters.payload = append(filters.payload, own...)
+	}
+
+	return &filters, nil
+}
+
+func (f *deploymentFilters) matches(deployment *Deployment) bool {
+	if !f.ref.matches(deployment.GetRef()) ||
+		!f.task.matches(deployment.GetTask()) ||
+		!f.environment.matches(deployment.GetEnvironment()) ||
+		!f.creator.matches(deployment.GetCreator().GetLogin()) {
+		return false
+	}
+
+	if f.productionOnly && !deployment.GetProductionEnvironment() {
+		return false
+	}
+	if f.excludeTransient && deployment.GetTransientEnvironment() {
+		return false
+	}
+
+	if len(f.payload) == 0 {
+		return true
+	}
+
+	payload := decodePayload(deployment)
+	for _, filter := range f.payload {
+		if !filter.matches(payload) {
+			return false
+		}
+	}
+
+	return true
+}
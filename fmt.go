@@ -9,8 +9,8 @@ import (
 )
 
 func Fatal(doing string, err error) {
-	Sayf(colorstring.Color("[red]error %s: %s\n"), doing, err)
-	debug.PrintStack()
+	Sayf(colorstring.Color("[red]error %s: %s\n"), doing, Redact(err.Error()))
+	os.Stderr.WriteString(Redact(string(debug.Stack())))
 	os.Exit(1)
 }
 
@@ -0,0 +1,234 @@
+package resource
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+
+	git "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+)
+
+// repoRef is the commit checked out in a local git repository, and the ref
+// to deploy it by: a tag pointing at the commit, or otherwise its SHA.
+type repoRef struct {
+	Ref      string
+	SHA      string
+	ShortSHA string
+}
+
+// readRepoRef reads the commit checked out in the repository at repo, without
+// fetching anything. The git resource leaves HEAD detached, so its .git/ref
+// and .git/short_ref files are used when they exist. Otherwise HEAD must be
+// on a branch. Changes to tracked files fail the read unless allowDirty is
+// set, as the deployment would not match what is checked out.
+func readRepoRef(repo string, allowDirty bool) (repoRef, error) {
+	gitRepo, err := git.PlainOpen(repo)
+	if err != nil {
+		if err == git.ErrRepositoryNotExists {
+			return repoRef{}, errors.New("not a git repository")
+		}
+		return repoRef{}, err
+	}
+
+	ref, err := gitResourceRef(repo)
+	if err != nil {
+		return repoRef{}, err
+	}
+	if ref.SHA == "" {
+		ref, err = headRef(gitRepo)
+		if err != nil {
+			return repoRef{}, err
+		}
+	}
+
+	if !allowDirty {
+		if err := checkClean(gitRepo); err != nil {
+			return repoRef{}, err
+		}
+	}
+
+	tags, err := tagsFor(gitRepo, ref.SHA)
+	if err != nil {
+		return repoRef{}, err
+	}
+
+	ref.Ref = ref.SHA
+	if len(tags) > 0 {
+		ref.Ref = tags[len(tags)-1]
+	}
+	return ref, nil
+}
+
+// gitResourceRef reads the commit that the git resource fetched, or returns
+// an empty repoRef when the repository was not fetched by it.
+func gitResourceRef(repo string) (repoRef, error) {
+	sha, err := readTrimmed(filepath.Join(repo, ".git", "ref"))
+	if err != nil {
+		if os.IsNotExist(err) || errors.Is(err, syscall.ENOTDIR) {
+			return repoRef{}, nil
+		}
+		return repoRef{}, err
+	}
+
+	shortSHA, err := readTrimmed(filepath.Join(repo, ".git", "short_ref"))
+	if err != nil {
+		shortSHA = abbreviate(sha)
+	}
+	return repoRef{SHA: sha, ShortSHA: shortSHA}, nil
+}
+
+func headRef(gitRepo *git.Repository) (repoRef, error) {
+	head, err := gitRepo.Reference(plumbing.HEAD, false)
+	if err != nil {
+		return repoRef{}, err
+	}
+	if head.Type() != plumbing.SymbolicReference {
+		return repoRef{}, fmt.Errorf("HEAD is detached at %s; check out a branch, or get the repository with the git resource", abbreviate(head.Hash().String()))
+	}
+
+	resolved, err := gitRepo.Reference(head.Target(), true)
+	if err != nil {
+		if err == plumbing.ErrReferenceNotFound {
+			return repoRef{}, fmt.Errorf("HEAD is on %s, which has no commits", head.Target().Short())
+		}
+		return repoRef{}, err
+	}
+
+	sha := resolved.Hash().String()
+	return repoRef{SHA: sha, ShortSHA: abbreviate(sha)}, nil
+}
+
+// checkClean fails when tracked files in the working tree or the index differ
+// from the commit checked out. Untracked files are ignored, as builds often
+// leave them behind.
+func checkClean(gitRepo *git.Repository) error {
+	worktree, err := gitRepo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+
+	changed := []string{}
+	for path, file := range status {
+		if file.Worktree == git.Untracked {
+			continue
+		}
+		if file.Worktree != git.Unmodified || file.Staging != git.Unmodified {
+			changed = append(changed, path)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	sort.Strings(changed)
+
+	const maxListed = 5
+	listed := strings.Join(changed, ", ")
+	if len(changed) > maxListed {
+		listed = fmt.Sprintf("%s and %d more", strings.Join(changed[:maxListed], ", "), len(changed)-maxListed)
+	}
+	return fmt.Errorf("working tree has uncommitted changes to %s; commit them, or set allow_dirty", listed)
+}
+
+// tagsFor returns the names of the tags that point at the commit sha, from
+// the lowest version to the highest.
+func tagsFor(gitRepo *git.Repository, sha string) ([]string, error) {
+	tags, err := gitRepo.Tags()
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		target := ref.Hash()
+		if tag, err := gitRepo.TagObject(target); err == nil {
+			commit, err := tag.Commit()
+			if err != nil {
+				return nil
+			}
+			target = commit.Hash
+		}
+
+		if target.String() == sha {
+			names = append(names, ref.Name().Short())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return versionLess(names[i], names[j])
+	})
+	return names, nil
+}
+
+// versionLess orders tags as versions, comparing runs of digits as numbers
+// so that v1.10 comes after v1.9, and putting pre-releases such as
+// v1.10-rc1 before the release.
+func versionLess(a, b string) bool {
+	for a != "" && b != "" {
+		aPart, aRest := versionPart(a)
+		bPart, bRest := versionPart(b)
+
+		if aPart != bPart {
+			if isDigits(aPart) && isDigits(bPart) {
+				aNum, bNum := strings.TrimLeft(aPart, "0"), strings.TrimLeft(bPart, "0")
+				if len(aNum) != len(bNum) {
+					return len(aNum) < len(bNum)
+				}
+				if aNum != bNum {
+					return aNum < bNum
+				}
+			} else {
+				return aPart < bPart
+			}
+		}
+
+		a, b = aRest, bRest
+	}
+
+	if a == "" {
+		return b != "" && !strings.HasPrefix(b, "-")
+	}
+	return strings.HasPrefix(a, "-")
+}
+
+// versionPart splits the leading run of digits, or of anything else, from s.
+func versionPart(s string) (string, string) {
+	digits := isDigits(s[:1])
+	i := 1
+	for i < len(s) && isDigits(s[i:i+1]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+func isDigits(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
+
+func readTrimmed(path string) (string, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+func abbreviate(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
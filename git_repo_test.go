@@ -0,0 +1,187 @@
+package resource_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Reading ref from a git repository", func() {
+	var (
+		sourceDir string
+		repo      string
+	)
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+		Ω(err).ShouldNot(HaveOccurred(), string(output))
+		return strings.TrimSpace(string(output))
+	}
+
+	resolve := func(params string) (resource.OutParams, error) {
+		var p resource.OutParams
+		Ω(json.Unmarshal([]byte(params), &p)).Should(Succeed())
+		return p, p.Resolve(sourceDir)
+	}
+
+	BeforeEach(func() {
+		if _, err := exec.LookPath("git"); err != nil {
+			Skip("git is not installed")
+		}
+
+		var err error
+		sourceDir, err = ioutil.TempDir("", "github-deployment")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		repo = filepath.Join(sourceDir, "repo")
+		Ω(os.Mkdir(repo, 0755)).Should(Succeed())
+
+		git("init", "-q")
+		git("checkout", "-q", "-b", "main")
+		file(filepath.Join(repo, "README.md"), "hello")
+		git("add", "README.md")
+		git("commit", "-q", "-m", "first")
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(sourceDir)).Should(Succeed())
+	})
+
+	It("uses the commit checked out on a branch", func() {
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo", "description": "{{.ShortRef}}"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		sha := git("rev-parse", "HEAD")
+		Ω(*p.Ref).Should(Equal(sha))
+		Ω(*p.Description).Should(Equal(sha[:7]))
+	})
+
+	It("uses a tag that points at the commit", func() {
+		git("tag", "-a", "-m", "release", "v1.0.0")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal("v1.0.0"))
+	})
+
+	It("uses the highest version when several tags point at the commit", func() {
+		git("tag", "v1.9.0")
+		git("tag", "-a", "-m", "release", "v1.10.0")
+		git("tag", "v1.10.0-rc1")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal("v1.10.0"))
+	})
+
+	It("ignores tags on other commits", func() {
+		git("tag", "v1.0.0")
+		git("commit", "-q", "--allow-empty", "-m", "second")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal(git("rev-parse", "HEAD")))
+	})
+
+	It("reads packed refs and tags", func() {
+		git("tag", "-a", "-m", "release", "v1.0.0")
+		git("pack-refs", "--all")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal("v1.0.0"))
+	})
+
+	It("uses the ref written by the git resource", func() {
+		sha := git("rev-parse", "HEAD")
+		git("checkout", "-q", sha)
+		file(filepath.Join(repo, ".git", "ref"), sha+"\n")
+		file(filepath.Join(repo, ".git", "short_ref"), "abc1234\n")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo", "description": "{{.ShortRef}}"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal(sha))
+		Ω(*p.Description).Should(Equal("abc1234"))
+	})
+
+	It("fails when HEAD is detached", func() {
+		sha := git("rev-parse", "HEAD")
+		git("checkout", "-q", sha)
+
+		_, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).Should(MatchError("reading ref_from_repo from repo: HEAD is detached at " + sha[:7] +
+			"; check out a branch, or get the repository with the git resource"))
+	})
+
+	It("fails when tracked files have uncommitted changes", func() {
+		file(filepath.Join(repo, "README.md"), "goodbye")
+
+		_, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).Should(MatchError("reading ref_from_repo from repo: working tree has uncommitted changes to README.md; " +
+			"commit them, or set allow_dirty"))
+	})
+
+	It("fails when changes are staged", func() {
+		file(filepath.Join(repo, "CHANGELOG.md"), "v1.0.0")
+		git("add", "CHANGELOG.md")
+
+		_, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).Should(MatchError("reading ref_from_repo from repo: working tree has uncommitted changes to CHANGELOG.md; " +
+			"commit them, or set allow_dirty"))
+	})
+
+	It("lists only the first few changed files", func() {
+		for _, name := range []string{"a", "b", "c", "d", "e", "f", "g"} {
+			file(filepath.Join(repo, name), name)
+		}
+		git("add", ".")
+
+		_, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).Should(MatchError("reading ref_from_repo from repo: working tree has uncommitted changes to a, b, c, d, e and 2 more; " +
+			"commit them, or set allow_dirty"))
+	})
+
+	It("ignores untracked files", func() {
+		file(filepath.Join(repo, "build.log"), "output")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal(git("rev-parse", "HEAD")))
+	})
+
+	It("allows uncommitted changes with allow_dirty", func() {
+		file(filepath.Join(repo, "README.md"), "goodbye")
+
+		p, err := resolve(`{"type": "deployment", "ref_from_repo": "repo", "allow_dirty": true}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal(git("rev-parse", "HEAD")))
+	})
+
+	It("requires ref_from_repo for allow_dirty", func() {
+		p, err := resolve(`{"type": "deployment", "ref": "main", "allow_dirty": true}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Validate()).Should(MatchError("invalid params:\n  allow_dirty requires ref_from_repo"))
+	})
+
+	It("fails when the path is not a git repository", func() {
+		_, err := resolve(`{"type": "deployment", "ref_from_repo": "missing"}`)
+		Ω(err).Should(MatchError("reading ref_from_repo from missing: not a git repository"))
+	})
+
+	It("cannot be used with ref", func() {
+		p, err := resolve(`{"type": "deployment", "ref": "main", "ref_from_repo": "repo"}`)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(*p.Ref).Should(Equal("main"))
+		Ω(p.Validate()).Should(MatchError("invalid params:\n  only one of ref or ref_from_repo can be set"))
+	})
+})
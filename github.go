@@ -1,10 +1,15 @@
 package resource
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
-	"time"
+	"strconv"
 
 	"golang.org/x/oauth2"
 
@@ -14,28 +19,114 @@ import (
 //go:generate counterfeiter -o fakes/fake_git_hub.go . GitHub
 
 type GitHub interface {
-	ListDeployments() ([]*github.Deployment, error)
-	ListDeploymentStatuses(ID int64) ([]*github.DeploymentStatus, error)
-	GetDeployment(ID int64) (*github.Deployment, error)
-	CreateDeployment(request *github.DeploymentRequest) (*github.Deployment, error)
-	CreateDeploymentStatus(ID int64, request *github.DeploymentStatusRequest) (*github.DeploymentStatus, error)
+	ListDeployments(ctx context.Context, opts ListDeploymentsOptions) ([]*Deployment, error)
+	ListDeploymentStatuses(ctx context.Context, ID int64) ([]*github.DeploymentStatus, error)
+	GetLatestDeploymentStatus(ctx context.Context, ID int64) (*github.DeploymentStatus, error)
+	GetDeployment(ctx context.Context, ID int64) (*Deployment, error)
+	CreateDeployment(ctx context.Context, request *github.DeploymentRequest) (*Deployment, error)
+	CreateDeploymentStatus(ctx context.Context, ID int64, request *DeploymentStatusRequest) (*github.DeploymentStatus, error)
+	DeleteDeployment(ctx context.Context, ID int64) error
 }
 
+//go:generate counterfeiter -o fakes/fake_environments.go . Environments
+
+// Environments manages the environments that deployments target, along with
+// their protection rules and deployment branch policies.
+type Environments interface {
+	GetEnvironment(ctx context.Context, name string) (*Environment, error)
+	CreateOrUpdateEnvironment(ctx context.Context, name string, request *EnvironmentRequest) (*Environment, error)
+	DeleteEnvironment(ctx context.Context, name string) error
+	ListDeploymentBranchPolicies(ctx context.Context, environment string) ([]*DeploymentBranchPolicy, error)
+	CreateDeploymentBranchPolicy(ctx context.Context, environment string, name string) (*DeploymentBranchPolicy, error)
+	DeleteDeploymentBranchPolicy(ctx context.Context, environment string, ID int64) error
+}
+
+// Deployment is a GitHub deployment along with the environment flags that
+// go-github does not decode.
+type Deployment struct {
+	*github.Deployment
+
+	TransientEnvironment  *bool `json:"transient_environment,omitempty"`
+	ProductionEnvironment *bool `json:"production_environment,omitempty"`
+}
+
+// GetTransientEnvironment returns the TransientEnvironment flag if it is set
+// and false otherwise.
+func (d *Deployment) GetTransientEnvironment() bool {
+	if d == nil || d.TransientEnvironment == nil {
+		return false
+	}
+	return *d.TransientEnvironment
+}
+
+// GetProductionEnvironment returns the ProductionEnvironment flag if it is
+// set and false otherwise.
+func (d *Deployment) GetProductionEnvironment() bool {
+	if d == nil || d.ProductionEnvironment == nil {
+		return false
+	}
+	return *d.ProductionEnvironment
+}
+
+// DeploymentStatusRequest creates a deployment status. It replaces the
+// go-github type, which cannot set a target_url.
+type DeploymentStatusRequest struct {
+	State          *string `json:"state,omitempty"`
+	Description    *string `json:"description,omitempty"`
+	Environment    *string `json:"environment,omitempty"`
+	EnvironmentURL *string `json:"environment_url,omitempty"`
+	LogURL         *string `json:"log_url,omitempty"`
+	TargetURL      *string `json:"target_url,omitempty"`
+	AutoInactive   *bool   `json:"auto_inactive,omitempty"`
+}
+
+// The ant-man preview includes the environment flags in deployments and
+// allows the newer status fields, and the flash preview allows the queued and
+// in_progress states.
+const mediaTypeDeploymentPreview = "application/vnd.github.ant-man-preview+json, application/vnd.github.flash-preview+json"
+
+// ListDeploymentsOptions filters the deployments returned by ListDeployments
+// and controls how far back it pages.
+type ListDeploymentsOptions struct {
+	// Environment, Ref, SHA and Task are passed to the GitHub API, which
+	// only returns deployments matching all of the non-empty values.
+	Environment string
+	Ref         string
+	SHA         string
+	Task        string
+
+	// MinID stops paging once a page contains a deployment with a lower ID.
+	// Deployments are listed newest first, so later pages can only be older.
+	MinID int64
+}
+
+const defaultPageSize = 100
+
 type GitHubClient struct {
 	client *github.Client
 
+	// httpClient is the client that client sends its requests with, for the
+	// responses that go-github does not decode fully.
+	httpClient *http.Client
+
 	user       string
 	repository string
-}
 
-func NewGitHubClient(source Source) (*GitHubClient, error) {
-	var client *github.Client
+	pageSize int
+	maxPages int
+}
 
-	client, err := oauthClient(source)
+// NewGitHubClient returns a client for the source's repository. ctx is used
+// for requests that are not made by an API call, such as those for GitHub
+// App installation tokens, so it should be the run context.
+func NewGitHubClient(ctx context.Context, source Source, writer io.Writer) (*GitHubClient, error) {
+	httpClient, err := oauthClient(ctx, source, writer)
 	if err != nil {
 		return nil, err
 	}
 
+	client := github.NewClient(httpClient)
+
 	if source.GitHubAPIURL != "" {
 		client.BaseURL, err = url.Parse(source.GitHubAPIURL)
 		if err != nil {
@@ -43,39 +134,96 @@ func NewGitHubClient(source Source) (*GitHubClient, error) {
 		}
 	}
 
+	pageSize := source.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
 	return &GitHubClient{
 		client:     client,
+		httpClient: httpClient,
 		user:       source.User,
 		repository: source.Repository,
+		pageSize:   pageSize,
+		maxPages:   source.MaxPages,
 	}, nil
 }
 
-func (g *GitHubClient) ListDeployments() ([]*github.Deployment, error) {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+func (g *GitHubClient) ListDeployments(ctx context.Context, opts ListDeploymentsOptions) ([]*Deployment, error) {
+	listOptions := &github.DeploymentsListOptions{
+		Environment: opts.Environment,
+		Ref:         opts.Ref,
+		SHA:         opts.SHA,
+		Task:        opts.Task,
+		ListOptions: github.ListOptions{PerPage: g.pageSize},
+	}
+
+	allDeployments := []*Deployment{}
+	for page := 1; ; page++ {
+		deployments, res, err := g.listDeploymentsPage(ctx, listOptions)
+		if err != nil {
+			return []*Deployment{}, err
+		}
+
+		allDeployments = append(allDeployments, deployments...)
+
+		if res.NextPage == 0 || g.reachedMaxPages(page) || containsOlderThan(deployments, opts.MinID) {
+			break
+		}
+		listOptions.Page = res.NextPage
+	}
+
+	return allDeployments, nil
+}
+
+func (g *GitHubClient) listDeploymentsPage(ctx context.Context, listOptions *github.DeploymentsListOptions) ([]*Deployment, *github.Response, error) {
+	params := url.Values{}
+	for name, value := range map[string]string{
+		"environment": listOptions.Environment,
+		"ref":         listOptions.Ref,
+		"sha":         listOptions.SHA,
+		"task":        listOptions.Task,
+	} {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+	params.Set("per_page", strconv.Itoa(listOptions.PerPage))
+	if listOptions.Page > 0 {
+		params.Set("page", strconv.Itoa(listOptions.Page))
+	}
 
-	deployments, res, err := g.client.Repositories.ListDeployments(ctx, g.user, g.repository, nil)
+	u := fmt.Sprintf("repos/%v/%v/deployments?%s", g.user, g.repository, params.Encode())
+	req, err := g.newDeploymentRequest("GET", u, nil)
 	if err != nil {
-		return []*github.Deployment{}, err
+		return nil, nil, err
+	}
+
+	var deployments []*Deployment
+	res, err := g.client.Do(ctx, req, &deployments)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	err = res.Body.Close()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return deployments, nil
+	return deployments, res, nil
 }
 
-func (g *GitHubClient) GetDeployment(ID int64) (*github.Deployment, error) {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+func (g *GitHubClient) GetDeployment(ctx context.Context, ID int64) (*Deployment, error) {
+	u := fmt.Sprintf("repos/%v/%v/deployments/%v", g.user, g.repository, ID)
+	req, err := g.newDeploymentRequest("GET", u, nil)
+	if err != nil {
+		return &Deployment{}, err
+	}
 
-	deployment, res, err := g.client.Repositories.GetDeployment(ctx, g.user, g.repository, ID)
+	deployment := &Deployment{}
+	res, err := g.client.Do(ctx, req, deployment)
 	if err != nil {
-		return &github.Deployment{}, err
+		return &Deployment{}, err
 	}
 
 	err = res.Body.Close()
@@ -86,14 +234,25 @@ func (g *GitHubClient) GetDeployment(ID int64) (*github.Deployment, error) {
 	return deployment, nil
 }
 
-func (g *GitHubClient) CreateDeployment(request *github.DeploymentRequest) (*github.Deployment, error) {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+// CreateDeployment creates a deployment. When GitHub refuses because the
+// ref's commit status checks have not passed, a *RequiredContextsError is
+// returned. The request is sent without go-github's Do, which drops the
+// contexts that GitHub lists in the response.
+func (g *GitHubClient) CreateDeployment(ctx context.Context, request *github.DeploymentRequest) (*Deployment, error) {
+	u := fmt.Sprintf("repos/%v/%v/deployments", g.user, g.repository)
+	req, err := g.newDeploymentRequest("POST", u, request)
+	if err != nil {
+		return &Deployment{}, err
+	}
+
+	res, err := g.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return &Deployment{}, err
+	}
 
-	deployment, res, err := g.client.Repositories.CreateDeployment(ctx, g.user, g.repository, request)
+	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return &github.Deployment{}, err
+		return &Deployment{}, err
 	}
 
 	err = res.Body.Close()
@@ -101,33 +260,120 @@ func (g *GitHubClient) CreateDeployment(request *github.DeploymentRequest) (*git
 		return nil, err
 	}
 
+	res.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err := github.CheckResponse(res); err != nil {
+		return &Deployment{}, requiredContextsError(request.GetRef(), err, body)
+	}
+
+	deployment := &Deployment{}
+	if err := json.Unmarshal(body, deployment); err != nil {
+		return &Deployment{}, err
+	}
+
 	return deployment, nil
 }
 
-func (g *GitHubClient) ListDeploymentStatuses(ID int64) ([]*github.DeploymentStatus, error) {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+// requiredContextsError returns a *RequiredContextsError when err is GitHub
+// refusing to create a deployment because the required contexts have not
+// succeeded, listing them from the response body. With auto_merge, GitHub
+// also refuses with a conflict when the default branch cannot be merged, so
+// any other error is returned as it is.
+func requiredContextsError(ref string, err error, body []byte) error {
+	errResp, ok := err.(*github.ErrorResponse)
+	if !ok || errResp.Response.StatusCode != http.StatusConflict {
+		return err
+	}
+
+	var conflict struct {
+		Errors []struct {
+			Field    string         `json:"field"`
+			Contexts []ContextState `json:"contexts"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &conflict) != nil {
+		return err
+	}
+
+	failing := &RequiredContextsError{Ref: ref}
+	found := false
+	for _, e := range conflict.Errors {
+		if e.Field != "required_contexts" {
+			continue
+		}
+		found = true
+
+		for _, state := range e.Contexts {
+			if state.State != "success" {
+				failing.Contexts = append(failing.Contexts, state)
+			}
+		}
+	}
+
+	if !found || len(failing.Contexts) == 0 {
+		return err
+	}
+	return failing
+}
+
+func (g *GitHubClient) ListDeploymentStatuses(ctx context.Context, ID int64) ([]*github.DeploymentStatus, error) {
+	listOptions := &github.ListOptions{PerPage: g.pageSize}
+
+	allStatuses := []*github.DeploymentStatus{}
+	for page := 1; ; page++ {
+		statuses, res, err := g.listDeploymentStatusesPage(ctx, ID, listOptions)
+		if err != nil {
+			return []*github.DeploymentStatus{}, err
+		}
+
+		allStatuses = append(allStatuses, statuses...)
 
-	statuses, res, err := g.client.Repositories.ListDeploymentStatuses(ctx, g.user, g.repository, ID, nil)
+		if res.NextPage == 0 || g.reachedMaxPages(page) {
+			break
+		}
+		listOptions.Page = res.NextPage
+	}
+
+	return allStatuses, nil
+}
+
+func (g *GitHubClient) listDeploymentStatusesPage(ctx context.Context, ID int64, listOptions *github.ListOptions) ([]*github.DeploymentStatus, *github.Response, error) {
+	statuses, res, err := g.client.Repositories.ListDeploymentStatuses(ctx, g.user, g.repository, ID, listOptions)
 	if err != nil {
-		return []*github.DeploymentStatus{}, err
+		return nil, nil, err
 	}
 
 	err = res.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return statuses, res, nil
+}
+
+// GetLatestDeploymentStatus returns the most recent status of a deployment, or
+// nil when it has none. GitHub lists statuses newest first, so only one is
+// requested, however many the deployment has.
+func (g *GitHubClient) GetLatestDeploymentStatus(ctx context.Context, ID int64) (*github.DeploymentStatus, error) {
+	statuses, _, err := g.listDeploymentStatusesPage(ctx, ID, &github.ListOptions{PerPage: 1})
 	if err != nil {
 		return nil, err
 	}
 
-	return statuses, nil
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+	return statuses[0], nil
 }
 
-func (g *GitHubClient) CreateDeploymentStatus(ID int64, request *github.DeploymentStatusRequest) (*github.DeploymentStatus, error) {
-	ctx := context.Background()
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
+func (g *GitHubClient) CreateDeploymentStatus(ctx context.Context, ID int64, request *DeploymentStatusRequest) (*github.DeploymentStatus, error) {
+	u := fmt.Sprintf("repos/%v/%v/deployments/%v/statuses", g.user, g.repository, ID)
+	req, err := g.newDeploymentRequest("POST", u, request)
+	if err != nil {
+		return &github.DeploymentStatus{}, err
+	}
 
-	status, res, err := g.client.Repositories.CreateDeploymentStatus(ctx, g.user, g.repository, ID, request)
+	status := &github.DeploymentStatus{}
+	res, err := g.client.Do(ctx, req, status)
 	if err != nil {
 		return &github.DeploymentStatus{}, err
 	}
@@ -140,16 +386,93 @@ func (g *GitHubClient) CreateDeploymentStatus(ID int64, request *github.Deployme
 	return status, nil
 }
 
-func oauthClient(source Source) (*github.Client, error) {
-	ts := oauth2.StaticTokenSource(&oauth2.Token{
-		AccessToken: source.AccessToken,
+// DeleteDeployment deletes a deployment. GitHub only allows inactive
+// deployments to be deleted, unless it is the only deployment.
+func (g *GitHubClient) DeleteDeployment(ctx context.Context, ID int64) error {
+	u := fmt.Sprintf("repos/%v/%v/deployments/%v", g.user, g.repository, ID)
+	req, err := g.client.NewRequest("DELETE", u, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := g.client.Do(ctx, req, nil)
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+// newDeploymentRequest builds a request for the deployments API that asks for
+// the previews it needs.
+func (g *GitHubClient) newDeploymentRequest(method, u string, body interface{}) (*http.Request, error) {
+	req, err := g.client.NewRequest(method, u, body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Accept", mediaTypeDeploymentPreview)
+	return req, nil
+}
+
+func (g *GitHubClient) reachedMaxPages(page int) bool {
+	return g.maxPages > 0 && page >= g.maxPages
+}
+
+func containsOlderThan(deployments []*Deployment, minID int64) bool {
+	for _, deployment := range deployments {
+		if deployment.GetID() < minID {
+			return true
+		}
+	}
+	return false
+}
+
+func oauthClient(ctx context.Context, source Source, writer io.Writer) (*http.Client, error) {
+	addSecret(source.AccessToken)
+	addSecret(source.PrivateKey)
+	addSecret(source.ClientKey)
+
+	transport, err := baseTransport(source, writer)
+	if err != nil {
+		return nil, err
+	}
+
+	var base http.RoundTripper = transport
+	if source.Debug {
+		base = &debugTransport{base: transport, writer: writer}
+	}
+
+	// Every request, including those made to fetch GitHub App tokens, goes
+	// through the retrying transport. The cache sits above it, so that it
+	// sees the Authorization header and only caches the final response.
+	var roundTripper http.RoundTripper = newRetryTransport(base, writer, source)
+	if source.CacheDir != "" {
+		roundTripper = &cacheTransport{base: roundTripper, dir: source.CacheDir}
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, &http.Client{
+		Transport: roundTripper,
 	})
 
-	oauthClient := oauth2.NewClient(oauth2.NoContext, ts)
+	ts, err := tokenSource(ctx, source)
+	if err != nil {
+		return nil, err
+	}
 
-	githubHTTPClient := &http.Client{
+	oauthClient := oauth2.NewClient(ctx, ts)
+
+	return &http.Client{
 		Transport: oauthClient.Transport,
+	}, nil
+}
+
+func tokenSource(ctx context.Context, source Source) (oauth2.TokenSource, error) {
+	if source.AppID != 0 {
+		return appTokenSource(ctx, source)
 	}
 
-	return github.NewClient(githubHTTPClient), nil
+	return oauth2.StaticTokenSource(&oauth2.Token{
+		AccessToken: source.AccessToken,
+	}), nil
 }
@@ -0,0 +1,161 @@
+package resource
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v28/github"
+	"golang.org/x/oauth2"
+)
+
+// GitHub rejects app JWTs that are valid for more than ten minutes, so stay
+// comfortably inside that and allow for some clock drift on the issued at time.
+const (
+	appJWTLifetime  = 9 * time.Minute
+	appJWTClockSkew = 60 * time.Second
+)
+
+// appJWTSource signs the short lived JWTs used to authenticate as a GitHub App.
+type appJWTSource struct {
+	appID int64
+	key   *rsa.PrivateKey
+	now   func() time.Time
+}
+
+func (s *appJWTSource) Token() (*oauth2.Token, error) {
+	now := s.now()
+	expiry := now.Add(appJWTLifetime)
+
+	header, err := json.Marshal(map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-appJWTClockSkew).Unix(),
+		"exp": expiry.Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{
+		AccessToken: unsigned + "." + base64.RawURLEncoding.EncodeToString(signature),
+		TokenType:   "Bearer",
+		Expiry:      expiry,
+	}, nil
+}
+
+// installationTokenSource exchanges app JWTs for installation access tokens.
+// It is wrapped in an oauth2.ReuseTokenSource so that a new installation token
+// is only requested once the previous one has expired. oauth2 does not pass
+// the context of the API call that needs the token, so the run context is
+// used instead, for cancellation and the source's timeout.
+type installationTokenSource struct {
+	ctx            context.Context
+	client         *github.Client
+	installationID int64
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	token, res, err := s.client.Apps.CreateInstallationToken(s.ctx, s.installationID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("creating installation token: %s", err)
+	}
+
+	err = res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	addSecret(token.GetToken())
+
+	t := &oauth2.Token{
+		AccessToken: token.GetToken(),
+	}
+	if token.ExpiresAt != nil {
+		t.Expiry = *token.ExpiresAt
+	}
+
+	return t, nil
+}
+
+func appTokenSource(ctx context.Context, source Source) (oauth2.TokenSource, error) {
+	if source.PrivateKey == "" {
+		return nil, errors.New("private_key is required when app_id is set")
+	}
+	if source.InstallationID == 0 {
+		return nil, errors.New("installation_id is required when app_id is set")
+	}
+
+	key, err := parsePrivateKey(source.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	jwtSource := oauth2.ReuseTokenSource(nil, &appJWTSource{
+		appID: source.AppID,
+		key:   key,
+		now:   time.Now,
+	})
+
+	appClient := github.NewClient(oauth2.NewClient(ctx, jwtSource))
+	if source.GitHubAPIURL != "" {
+		appClient.BaseURL, err = url.Parse(source.GitHubAPIURL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return oauth2.ReuseTokenSource(nil, &installationTokenSource{
+		ctx:            ctx,
+		client:         appClient,
+		installationID: source.InstallationID,
+	}), nil
+}
+
+func parsePrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(strings.TrimSpace(data)))
+	if block == nil {
+		return nil, errors.New("private_key is not a PEM encoded key")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing private_key: %s", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private_key must be an RSA key")
+	}
+
+	return key, nil
+}
@@ -0,0 +1,158 @@
+package resource_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("GitHub App authentication", func() {
+	var (
+		server *httptest.Server
+		source resource.Source
+
+		lock           sync.Mutex
+		tokenLifetime  time.Duration
+		tokensIssued   int
+		appAuthHeaders []string
+		apiAuthHeaders []string
+	)
+
+	BeforeEach(func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		privateKey := pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		})
+
+		tokenLifetime = time.Hour
+		tokensIssued = 0
+		appAuthHeaders = []string{}
+		apiAuthHeaders = []string{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			switch {
+			case r.Method == "POST" && r.URL.Path == "/app/installations/42/access_tokens":
+				appAuthHeaders = append(appAuthHeaders, r.Header.Get("Authorization"))
+				tokensIssued++
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprintf(w, `{"token":"installation-token-%d","expires_at":"%s"}`,
+					tokensIssued, time.Now().Add(tokenLifetime).UTC().Format(time.RFC3339))
+			case r.URL.Path == "/repos/owner/repo/deployments":
+				apiAuthHeaders = append(apiAuthHeaders, r.Header.Get("Authorization"))
+				fmt.Fprint(w, `[]`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}))
+
+		source = resource.Source{
+			User:           "owner",
+			Repository:     "repo",
+			GitHubAPIURL:   server.URL + "/",
+			AppID:          1234,
+			PrivateKey:     string(privateKey),
+			InstallationID: 42,
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("exchanges a signed JWT for an installation token", func() {
+		client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(appAuthHeaders).Should(HaveLen(1))
+		Ω(appAuthHeaders[0]).Should(HavePrefix("Bearer "))
+		Ω(strings.Split(strings.TrimPrefix(appAuthHeaders[0], "Bearer "), ".")).Should(HaveLen(3))
+
+		Ω(apiAuthHeaders).Should(Equal([]string{"Bearer installation-token-1"}))
+	})
+
+	It("reuses the installation token until it expires", func() {
+		client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(tokensIssued).Should(Equal(1))
+		Ω(apiAuthHeaders).Should(Equal([]string{
+			"Bearer installation-token-1",
+			"Bearer installation-token-1",
+		}))
+	})
+
+	It("refreshes the installation token once it has expired", func() {
+		tokenLifetime = time.Second
+
+		client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).ShouldNot(HaveOccurred())
+
+		Ω(tokensIssued).Should(Equal(2))
+		Ω(apiAuthHeaders).Should(Equal([]string{
+			"Bearer installation-token-1",
+			"Bearer installation-token-2",
+		}))
+	})
+
+	It("requests installation tokens with the client's context", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		client, err := resource.NewGitHubClient(ctx, source, ioutil.Discard)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		cancel()
+
+		_, err = client.ListDeployments(context.Background(), resource.ListDeploymentsOptions{})
+		Ω(err).Should(MatchError(ContainSubstring("context canceled")))
+		Ω(tokensIssued).Should(Equal(0))
+	})
+
+	It("requires a private key and installation id", func() {
+		source.PrivateKey = ""
+		_, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).Should(MatchError("private_key is required when app_id is set"))
+
+		source.PrivateKey = "not a key"
+		source.InstallationID = 0
+		_, err = resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).Should(MatchError("installation_id is required when app_id is set"))
+	})
+
+	It("rejects a private key that is not PEM encoded", func() {
+		source.PrivateKey = "not a key"
+		_, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+		Ω(err).Should(MatchError("private_key is not a PEM encoded key"))
+	})
+})
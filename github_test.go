@@ -0,0 +1,341 @@
+package resource_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("GitHub Client", func() {
+	var (
+		server *httptest.Server
+		source resource.Source
+
+		requestedPages []string
+	)
+
+	// pagedHandler serves numbered items three pages deep, newest first, with
+	// the same Link headers that GitHub sends.
+	pagedHandler := func(w http.ResponseWriter, r *http.Request) {
+		requestedPages = append(requestedPages, r.URL.RawQuery)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		if page == 0 {
+			page = 1
+		}
+
+		if page < 3 {
+			w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, server.URL, r.URL.Path, page+1))
+		}
+
+		items := []string{}
+		for i := 0; i < 2; i++ {
+			id := 7 - (page * 2) + (1 - i)
+			items = append(items, fmt.Sprintf(`{"id":%d}`, id))
+		}
+		fmt.Fprintf(w, "[%s]", strings.Join(items, ","))
+	}
+
+	BeforeEach(func() {
+		requestedPages = []string{}
+
+		server = httptest.NewServer(http.HandlerFunc(pagedHandler))
+
+		source = resource.Source{
+			User:         "owner",
+			Repository:   "repo",
+			GitHubAPIURL: server.URL + "/",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	deploymentIDs := func(client *resource.GitHubClient, opts resource.ListDeploymentsOptions) []int64 {
+		deployments, err := client.ListDeployments(context.Background(), opts)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		ids := []int64{}
+		for _, deployment := range deployments {
+			ids = append(ids, deployment.GetID())
+		}
+		return ids
+	}
+
+	Describe("ListDeployments", func() {
+		It("follows the Link headers through every page", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(deploymentIDs(client, resource.ListDeploymentsOptions{})).Should(Equal([]int64{6, 5, 4, 3, 2, 1}))
+			Ω(requestedPages).Should(HaveLen(3))
+		})
+
+		It("requests the configured page size", func() {
+			source.PageSize = 2
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deploymentIDs(client, resource.ListDeploymentsOptions{})
+			Ω(requestedPages[0]).Should(Equal("per_page=2"))
+		})
+
+		It("defaults to the maximum page size", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deploymentIDs(client, resource.ListDeploymentsOptions{})
+			Ω(requestedPages[0]).Should(Equal("per_page=100"))
+		})
+
+		It("stops once the maximum number of pages has been fetched", func() {
+			source.MaxPages = 2
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(deploymentIDs(client, resource.ListDeploymentsOptions{})).Should(Equal([]int64{6, 5, 4, 3}))
+			Ω(requestedPages).Should(HaveLen(2))
+		})
+
+		It("passes the filters on to GitHub", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deploymentIDs(client, resource.ListDeploymentsOptions{
+				Environment: "production",
+				Ref:         "master",
+				SHA:         "abc123",
+				Task:        "deploy:migrations",
+			})
+			Ω(requestedPages[0]).Should(Equal("environment=production&per_page=100&ref=master&sha=abc123&task=deploy%3Amigrations"))
+		})
+
+		It("stops once a page reaches deployments older than MinID", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(deploymentIDs(client, resource.ListDeploymentsOptions{MinID: 5})).Should(Equal([]int64{6, 5, 4, 3}))
+			Ω(requestedPages).Should(HaveLen(2))
+		})
+	})
+
+	Describe("GetDeployment", func() {
+		It("asks for and decodes the environment flags", func() {
+			var accept string
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept")
+				fmt.Fprint(w, `{"id":42,"transient_environment":true,"production_environment":false}`)
+			})
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			deployment, err := client.GetDeployment(context.Background(), 42)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(accept).Should(Equal("application/vnd.github.ant-man-preview+json, application/vnd.github.flash-preview+json"))
+			Ω(deployment.GetID()).Should(Equal(int64(42)))
+			Ω(deployment.GetTransientEnvironment()).Should(BeTrue())
+			Ω(deployment.GetProductionEnvironment()).Should(BeFalse())
+		})
+	})
+
+	Describe("CreateDeployment", func() {
+		conflict := func(body string) {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+				fmt.Fprint(w, body)
+			})
+		}
+
+		create := func() (*resource.Deployment, error) {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			return client.CreateDeployment(context.Background(), &github.DeploymentRequest{
+				Ref:       github.String("master"),
+				AutoMerge: github.Bool(true),
+			})
+		}
+
+		It("creates the deployment and asks for the previews", func() {
+			var accept, body string
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept")
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				w.WriteHeader(http.StatusCreated)
+				fmt.Fprint(w, `{"id":42,"ref":"master","transient_environment":true}`)
+			})
+
+			deployment, err := create()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(deployment.GetID()).Should(Equal(int64(42)))
+			Ω(deployment.GetTransientEnvironment()).Should(BeTrue())
+			Ω(accept).Should(ContainSubstring("application/vnd.github.ant-man-preview+json"))
+			Ω(body).Should(MatchJSON(`{"ref":"master","auto_merge":true}`))
+		})
+
+		It("reports the required contexts that have not succeeded", func() {
+			conflict(`{
+				"message": "Conflict: Commit status checks failed for master.",
+				"errors": [{
+					"contexts": [
+						{"context": "ci/build", "state": "success"},
+						{"context": "ci/lint", "state": "failure"},
+						{"context": "Lint / eslint", "state": "pending"}
+					],
+					"resource": "Deployment",
+					"field": "required_contexts",
+					"code": "invalid"
+				}]
+			}`)
+
+			_, err := create()
+			Ω(err).Should(BeAssignableToTypeOf(&resource.RequiredContextsError{}))
+			Ω(err).Should(MatchError("commit status checks have not passed for master:\n" +
+				"  ci/lint: failure\n" +
+				"  Lint / eslint: pending"))
+		})
+
+		It("returns GitHub's error for other conflicts", func() {
+			conflict(`{"message": "Conflict merging master into feature."}`)
+
+			_, err := create()
+			Ω(err).Should(BeAssignableToTypeOf(&github.ErrorResponse{}))
+			Ω(err.Error()).Should(ContainSubstring("Conflict merging master into feature."))
+		})
+
+		It("returns GitHub's error when no failing contexts are listed", func() {
+			conflict(`{"message": "Conflict: Commit status checks failed for master.", "errors": [{"field": "required_contexts", "code": "invalid"}]}`)
+
+			_, err := create()
+			Ω(err).Should(BeAssignableToTypeOf(&github.ErrorResponse{}))
+		})
+	})
+
+	Describe("CreateDeploymentStatus", func() {
+		It("sends the target url and asks for the previews", func() {
+			var accept, body string
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				accept = r.Header.Get("Accept")
+				b, _ := ioutil.ReadAll(r.Body)
+				body = string(b)
+				fmt.Fprint(w, `{"id":1,"state":"queued"}`)
+			})
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.CreateDeploymentStatus(context.Background(), 42, &resource.DeploymentStatusRequest{
+				State:     github.String("queued"),
+				TargetURL: github.String("https://ci.example.com"),
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(accept).Should(ContainSubstring("application/vnd.github.flash-preview+json"))
+			Ω(body).Should(MatchJSON(`{"state":"queued","target_url":"https://ci.example.com"}`))
+		})
+	})
+
+	Describe("ListDeploymentStatuses", func() {
+		It("follows the Link headers through every page", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			statuses, err := client.ListDeploymentStatuses(context.Background(), 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(statuses).Should(HaveLen(6))
+			Ω(requestedPages).Should(HaveLen(3))
+		})
+	})
+
+	Describe("GetLatestDeploymentStatus", func() {
+		It("only requests the newest status", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			status, err := client.GetLatestDeploymentStatus(context.Background(), 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(status.GetID()).Should(Equal(int64(6)))
+			Ω(requestedPages).Should(Equal([]string{"per_page=1"}))
+		})
+
+		It("returns nil when the deployment has no statuses", func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "[]")
+			})
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			status, err := client.GetLatestDeploymentStatus(context.Background(), 1)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(status).Should(BeNil())
+		})
+	})
+
+	Describe("DeleteDeployment", func() {
+		It("deletes the deployment", func() {
+			var method, path string
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				method, path = r.Method, r.URL.Path
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(client.DeleteDeployment(context.Background(), 42)).Should(Succeed())
+			Ω(method).Should(Equal("DELETE"))
+			Ω(path).Should(Equal("/repos/owner/repo/deployments/42"))
+		})
+	})
+
+	Describe("timeouts", func() {
+		BeforeEach(func() {
+			server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-time.After(time.Second):
+				case <-r.Context().Done():
+				}
+				fmt.Fprint(w, `{"id":1}`)
+			})
+		})
+
+		It("gives up on requests that take longer than the request timeout", func() {
+			source.RequestTimeout = &resource.Duration{Duration: 50 * time.Millisecond}
+			source.MaxRetries = github.Int(0)
+
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = client.GetDeployment(context.Background(), 1)
+			Ω(err).Should(MatchError(ContainSubstring("context deadline exceeded")))
+		})
+
+		It("stops when the context is cancelled", func() {
+			client, err := resource.NewGitHubClient(context.Background(), source, ioutil.Discard)
+			Ω(err).ShouldNot(HaveOccurred())
+
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+
+			_, err = client.GetDeployment(ctx, 1)
+			Ω(err).Should(MatchError(ContainSubstring("context canceled")))
+		})
+	})
+})
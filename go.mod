@@ -3,10 +3,12 @@ module github.com/ahume/github-deployment-resource
 go 1.13
 
 require (
+	github.com/go-git/go-git/v5 v5.2.0
 	github.com/google/go-github/v28 v28.1.1
 	github.com/mitchellh/colorstring v0.0.0-20190213212951-d06e56a500db
 	github.com/onsi/ginkgo v1.10.1
 	github.com/onsi/gomega v1.7.0
 	github.com/peterbourgon/mergemap v0.0.0-20130613134717-e21c03b7a721
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
+	gopkg.in/yaml.v2 v2.2.4
 )
@@ -1,6 +1,7 @@
 package resource
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -23,15 +24,27 @@ func NewInCommand(github GitHub, writer io.Writer) *InCommand {
 	}
 }
 
-func (c *InCommand) Run(destDir string, request InRequest) (InResponse, error) {
+func (c *InCommand) Run(ctx context.Context, destDir string, request InRequest) (InResponse, error) {
 	err := os.MkdirAll(destDir, 0755)
 	if err != nil {
 		return InResponse{}, err
 	}
 
+	if request.Version.Environment != "" {
+		return c.environment(destDir, request.Version)
+	}
+
+	if request.Version.Deleted == "true" {
+		return c.deleted(destDir, request.Version)
+	}
+
+	if request.Version.Cleanup == "true" {
+		return c.cleanup(destDir, request.Version)
+	}
+
 	id, _ := strconv.ParseInt(request.Version.ID, 10, 64)
 	fmt.Fprintln(c.writer, "getting deployment")
-	deployment, err := c.github.GetDeployment(id)
+	deployment, err := c.github.GetDeployment(ctx, id)
 	if err != nil {
 		return InResponse{}, err
 	}
@@ -82,6 +95,22 @@ func (c *InCommand) Run(destDir string, request InRequest) (InResponse, error) {
 		}
 	}
 
+	if deployment.TransientEnvironment != nil {
+		transientPath := filepath.Join(destDir, "transient_environment")
+		err = ioutil.WriteFile(transientPath, []byte(strconv.FormatBool(*deployment.TransientEnvironment)), 0644)
+		if err != nil {
+			return InResponse{}, err
+		}
+	}
+
+	if deployment.ProductionEnvironment != nil {
+		productionPath := filepath.Join(destDir, "production_environment")
+		err = ioutil.WriteFile(productionPath, []byte(strconv.FormatBool(*deployment.ProductionEnvironment)), 0644)
+		if err != nil {
+			return InResponse{}, err
+		}
+	}
+
 	// Save the whole deployment too I guess.
 	deploymentPath := filepath.Join(destDir, "deploymentJSON")
 	deploymentJSON, _ := json.Marshal(deployment)
@@ -91,7 +120,7 @@ func (c *InCommand) Run(destDir string, request InRequest) (InResponse, error) {
 	}
 
 	fmt.Fprintln(c.writer, "getting deployment statuses list")
-	statuses, err := c.github.ListDeploymentStatuses(*deployment.ID)
+	statuses, err := c.github.ListDeploymentStatuses(ctx, *deployment.ID)
 	if err != nil {
 		return InResponse{}, err
 	}
@@ -109,3 +138,78 @@ func (c *InCommand) Run(destDir string, request InRequest) (InResponse, error) {
 		Metadata: metadataFromDeployment(deployment, statuses),
 	}, nil
 }
+
+// deleted handles the implicit get after type=delete. The deployment no
+// longer exists, so only its ID is written.
+func (c *InCommand) deleted(destDir string, version Version) (InResponse, error) {
+	fmt.Fprintln(c.writer, "deployment was deleted, not fetching it")
+
+	err := ioutil.WriteFile(filepath.Join(destDir, "id"), []byte(version.ID), 0644)
+	if err != nil {
+		return InResponse{}, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(destDir, "deleted"), []byte("true"), 0644)
+	if err != nil {
+		return InResponse{}, err
+	}
+
+	return InResponse{
+		Version: version,
+		Metadata: []MetadataPair{
+			{Name: "id", Value: version.ID},
+			{Name: "deleted", Value: "true"},
+		},
+	}, nil
+}
+
+// cleanup handles the implicit get after type=cleanup. The version's
+// deployment may have been deleted by the cleanup, so only its ID is written.
+func (c *InCommand) cleanup(destDir string, version Version) (InResponse, error) {
+	fmt.Fprintln(c.writer, "version is a cleanup, not fetching a deployment")
+
+	err := ioutil.WriteFile(filepath.Join(destDir, "id"), []byte(version.ID), 0644)
+	if err != nil {
+		return InResponse{}, err
+	}
+
+	err = ioutil.WriteFile(filepath.Join(destDir, "cleanup"), []byte("true"), 0644)
+	if err != nil {
+		return InResponse{}, err
+	}
+
+	return InResponse{
+		Version: version,
+		Metadata: []MetadataPair{
+			{Name: "id", Value: version.ID},
+			{Name: "cleanup", Value: "true"},
+		},
+	}, nil
+}
+
+// environment handles the implicit get after type=environment, which puts an
+// environment rather than a deployment. Nothing is fetched, and only the
+// environment's name and ID are written.
+func (c *InCommand) environment(destDir string, version Version) (InResponse, error) {
+	fmt.Fprintln(c.writer, "version is an environment, not fetching a deployment")
+
+	files := []MetadataPair{
+		{Name: "environment", Value: version.Environment},
+		{Name: "environment_id", Value: version.EnvironmentID},
+	}
+	if version.Deleted == "true" {
+		files = append(files, MetadataPair{Name: "deleted", Value: "true"})
+	}
+
+	for _, file := range files {
+		err := ioutil.WriteFile(filepath.Join(destDir, file.Name), []byte(file.Value), 0644)
+		if err != nil {
+			return InResponse{}, err
+		}
+	}
+
+	return InResponse{
+		Version:  version,
+		Metadata: files,
+	}, nil
+}
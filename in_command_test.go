@@ -1,6 +1,7 @@
 package resource_test
 
 import (
+	"context"
 	"errors"
 	"io/ioutil"
 	"os"
@@ -49,8 +50,8 @@ var _ = Describe("In Command", func() {
 		Ω(os.RemoveAll(tmpDir)).Should(Succeed())
 	})
 
-	buildDeployment := func(ID int64, env string, task string) *github.Deployment {
-		return &github.Deployment{
+	buildDeployment := func(ID int64, env string, task string) *resource.Deployment {
+		return &resource.Deployment{Deployment: &github.Deployment{
 			ID:          github.Int64(ID),
 			Environment: github.String(env),
 			Task:        github.String(task),
@@ -60,15 +61,15 @@ var _ = Describe("In Command", func() {
 			Creator: &github.User{
 				Login: github.String("Something"),
 			},
-			CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
-		}
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+		}}
 	}
 
 	buildDeploymentStatus := func(ID int64, state string) *github.DeploymentStatus {
 		return &github.DeploymentStatus{
 			ID:        github.Int64(ID),
 			State:     github.String(state),
-			CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
 		}
 	}
 
@@ -76,7 +77,7 @@ var _ = Describe("In Command", func() {
 		disaster := errors.New("no deployment")
 
 		BeforeEach(func() {
-			githubClient.GetDeploymentReturns(&github.Deployment{}, disaster)
+			githubClient.GetDeploymentReturns(&resource.Deployment{Deployment: &github.Deployment{}}, disaster)
 
 			inRequest.Version = resource.Version{
 				ID: "1",
@@ -84,7 +85,7 @@ var _ = Describe("In Command", func() {
 		})
 
 		It("returns an appropriate error", func() {
-			inResponse, inErr = command.Run(destDir, inRequest)
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
 
 			Expect(inErr).To(Equal(disaster))
 		})
@@ -103,7 +104,7 @@ var _ = Describe("In Command", func() {
 		})
 
 		It("creates the correct data files", func() {
-			inResponse, inErr = command.Run(destDir, inRequest)
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
 
 			contents, err := ioutil.ReadFile(path.Join(destDir, "id"))
 			Ω(err).ShouldNot(HaveOccurred())
@@ -131,7 +132,7 @@ var _ = Describe("In Command", func() {
 		})
 
 		It("outputs the correct metadata", func() {
-			inResponse, inErr = command.Run(destDir, inRequest)
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
 
 			Ω(inResponse.Metadata).Should(ConsistOf(
 				resource.MetadataPair{Name: "id", Value: "1"},
@@ -148,5 +149,148 @@ var _ = Describe("In Command", func() {
 				resource.MetadataPair{Name: "status_count", Value: "1"},
 			))
 		})
+
+		Context("when the deployment has environment flags", func() {
+			BeforeEach(func() {
+				deployment := buildDeployment(1, "review-12", "deploy")
+				deployment.TransientEnvironment = github.Bool(true)
+				deployment.ProductionEnvironment = github.Bool(false)
+				githubClient.GetDeploymentReturns(deployment, nil)
+			})
+
+			It("writes them to files", func() {
+				inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+				Ω(inErr).ShouldNot(HaveOccurred())
+
+				contents, err := ioutil.ReadFile(path.Join(destDir, "transient_environment"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal("true"))
+
+				contents, err = ioutil.ReadFile(path.Join(destDir, "production_environment"))
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(string(contents)).Should(Equal("false"))
+			})
+
+			It("includes them in the metadata", func() {
+				inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+				Ω(inErr).ShouldNot(HaveOccurred())
+
+				Ω(inResponse.Metadata).Should(ContainElement(resource.MetadataPair{Name: "transient_environment", Value: "true"}))
+				Ω(inResponse.Metadata).Should(ContainElement(resource.MetadataPair{Name: "production_environment", Value: "false"}))
+			})
+		})
+
+		It("does not write environment flags the deployment does not have", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			Ω(path.Join(destDir, "transient_environment")).ShouldNot(BeAnExistingFile())
+		})
+	})
+
+	Context("when the version was put by type=delete", func() {
+		BeforeEach(func() {
+			inRequest.Version = resource.Version{ID: "1234", Deleted: "true"}
+		})
+
+		It("does not look for the deployment", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.GetDeploymentCallCount()).Should(Equal(0))
+			Ω(inResponse.Version).Should(Equal(inRequest.Version))
+			Ω(inResponse.Metadata).Should(Equal([]resource.MetadataPair{
+				{Name: "id", Value: "1234"},
+				{Name: "deleted", Value: "true"},
+			}))
+		})
+
+		It("writes the id and that it was deleted", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path.Join(destDir, "id"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("1234"))
+
+			contents, err = ioutil.ReadFile(path.Join(destDir, "deleted"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("true"))
+		})
+	})
+
+	Context("when the version was put by type=cleanup", func() {
+		BeforeEach(func() {
+			inRequest.Version = resource.Version{ID: "1234", Cleanup: "true"}
+		})
+
+		It("does not look for the deployment", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.GetDeploymentCallCount()).Should(Equal(0))
+			Ω(inResponse.Version).Should(Equal(inRequest.Version))
+			Ω(inResponse.Metadata).Should(Equal([]resource.MetadataPair{
+				{Name: "id", Value: "1234"},
+				{Name: "cleanup", Value: "true"},
+			}))
+		})
+
+		It("writes the id and that it was a cleanup", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path.Join(destDir, "id"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("1234"))
+
+			contents, err = ioutil.ReadFile(path.Join(destDir, "cleanup"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("true"))
+		})
+	})
+
+	Context("when the version was put by type=environment", func() {
+		BeforeEach(func() {
+			inRequest.Version = resource.Version{Environment: "pr-123", EnvironmentID: "99"}
+		})
+
+		It("does not look for a deployment", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			Ω(githubClient.GetDeploymentCallCount()).Should(Equal(0))
+			Ω(inResponse.Version).Should(Equal(inRequest.Version))
+			Ω(inResponse.Metadata).Should(Equal([]resource.MetadataPair{
+				{Name: "environment", Value: "pr-123"},
+				{Name: "environment_id", Value: "99"},
+			}))
+		})
+
+		It("writes the environment's name and ID", func() {
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path.Join(destDir, "environment"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("pr-123"))
+
+			contents, err = ioutil.ReadFile(path.Join(destDir, "environment_id"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("99"))
+
+			Ω(path.Join(destDir, "id")).ShouldNot(BeAnExistingFile())
+		})
+
+		It("writes that a deleted environment was deleted", func() {
+			inRequest.Version.Deleted = "true"
+
+			inResponse, inErr = command.Run(context.Background(), destDir, inRequest)
+			Ω(inErr).ShouldNot(HaveOccurred())
+
+			contents, err := ioutil.ReadFile(path.Join(destDir, "deleted"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(string(contents)).Should(Equal("true"))
+		})
 	})
 })
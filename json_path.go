@@ -0,0 +1,95 @@
+package resource
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// lookupPath walks a dot separated path, such as "concourse_payload.build_name"
+// or ".deploys.0.version", through decoded JSON. Numeric segments index into
+// arrays. A leading dot is optional.
+func lookupPath(data interface{}, path string) (interface{}, bool) {
+	path = strings.TrimPrefix(path, ".")
+	if path == "" {
+		return data, true
+	}
+
+	current := data
+	for _, segment := range strings.Split(path, ".") {
+		switch value := current.(type) {
+		case map[string]interface{}:
+			next, ok := value[segment]
+			if !ok {
+				return nil, false
+			}
+			current = next
+		case []interface{}:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= len(value) {
+				return nil, false
+			}
+			current = value[index]
+		default:
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// extractPath decodes contents as JSON or YAML and returns the value at path
+// as a string. Strings are returned as they are, and any other value is
+// encoded as JSON.
+func extractPath(contents []byte, format, path string) (string, error) {
+	var data interface{}
+	switch format {
+	case "json":
+		if err := json.Unmarshal(contents, &data); err != nil {
+			return "", err
+		}
+	case "yaml":
+		if err := yaml.Unmarshal(contents, &data); err != nil {
+			return "", err
+		}
+		data = normalizeYAML(data)
+	}
+
+	value, found := lookupPath(data, path)
+	if !found {
+		return "", fmt.Errorf("%s_path %s not found", format, path)
+	}
+
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+// normalizeYAML converts the map[interface{}]interface{} values that the YAML
+// decoder produces into map[string]interface{}, as the JSON decoder does.
+func normalizeYAML(data interface{}) interface{} {
+	switch value := data.(type) {
+	case map[interface{}]interface{}:
+		normalized := map[string]interface{}{}
+		for k, v := range value {
+			normalized[fmt.Sprint(k)] = normalizeYAML(v)
+		}
+		return normalized
+	case []interface{}:
+		for i, v := range value {
+			value[i] = normalizeYAML(v)
+		}
+		return value
+	default:
+		return value
+	}
+}
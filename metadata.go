@@ -6,7 +6,7 @@ import (
 	"github.com/google/go-github/v28/github"
 )
 
-func metadataFromDeployment(deployment *github.Deployment, statuses []*github.DeploymentStatus) []MetadataPair {
+func metadataFromDeployment(deployment *Deployment, statuses []*github.DeploymentStatus) []MetadataPair {
 	metadata := []MetadataPair{}
 
 	if deployment.ID != nil {
@@ -58,6 +58,20 @@ func metadataFromDeployment(deployment *github.Deployment, statuses []*github.De
 		metadata = append(metadata, descMeta)
 	}
 
+	if deployment.TransientEnvironment != nil {
+		metadata = append(metadata, MetadataPair{
+			Name:  "transient_environment",
+			Value: strconv.FormatBool(*deployment.TransientEnvironment),
+		})
+	}
+
+	if deployment.ProductionEnvironment != nil {
+		metadata = append(metadata, MetadataPair{
+			Name:  "production_environment",
+			Value: strconv.FormatBool(*deployment.ProductionEnvironment),
+		})
+	}
+
 	if deployment.Creator != nil {
 		creatorMeta := MetadataPair{
 			Name:  "creator",
@@ -1,16 +1,18 @@
 package resource
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"strconv"
 	"strings"
-
-	"github.com/google/go-github/v28/github"
 )
 
+// StatusStates are the states a deployment status can have.
+var StatusStates = []string{"error", "failure", "inactive", "in_progress", "queued", "pending", "success"}
+
 type OutCommand struct {
 	github GitHub
 	writer io.Writer
@@ -23,37 +25,46 @@ func NewOutCommand(github GitHub, writer io.Writer) *OutCommand {
 	}
 }
 
-func (c *OutCommand) Run(sourceDir string, request OutRequest) (OutResponse, error) {
+func (c *OutCommand) Run(ctx context.Context, sourceDir string, request OutRequest) (OutResponse, error) {
 	if request.Params.ID == nil {
 		return OutResponse{}, errors.New("id is a required parameter")
 	}
 	if request.Params.State == nil {
 		return OutResponse{}, errors.New("state is a required parameter")
 	}
+	if !validStatusState(*request.Params.State) {
+		return OutResponse{}, fmt.Errorf("unknown state %q, must be one of %s",
+			*request.Params.State, strings.Join(StatusStates, ", "))
+	}
 
 	idInt, err := strconv.ParseInt(*request.Params.ID, 10, 64)
 	if err != nil {
 		return OutResponse{}, err
 	}
 	fmt.Fprintln(c.writer, "getting deployment")
-	deployment, err := c.github.GetDeployment(idInt)
+	deployment, err := c.github.GetDeployment(ctx, idInt)
 	if err != nil {
 		return OutResponse{}, err
 	}
 
-	newStatus := &github.DeploymentStatusRequest{
-		State:       request.Params.State,
-		Description: request.Params.Description,
+	newStatus := &DeploymentStatusRequest{
+		State:          request.Params.State,
+		Description:    request.Params.Description,
+		Environment:    request.Params.Environment,
+		EnvironmentURL: request.Params.EnvironmentURL,
+		LogURL:         request.Params.LogURL,
+		TargetURL:      request.Params.TargetURL,
+		AutoInactive:   request.Params.AutoInactive,
 	}
 
 	fmt.Fprintln(c.writer, "creating deployment status")
-	_, err = c.github.CreateDeploymentStatus(*deployment.ID, newStatus)
+	_, err = c.github.CreateDeploymentStatus(ctx, *deployment.ID, newStatus)
 	if err != nil {
 		return OutResponse{}, err
 	}
 
 	fmt.Fprintln(c.writer, "getting deployment statuses list")
-	statuses, err := c.github.ListDeploymentStatuses(*deployment.ID)
+	statuses, err := c.github.ListDeploymentStatuses(ctx, *deployment.ID)
 	if err != nil {
 		return OutResponse{}, err
 	}
@@ -72,6 +83,15 @@ func (c *OutCommand) Run(sourceDir string, request OutRequest) (OutResponse, err
 	}, nil
 }
 
+func validStatusState(state string) bool {
+	for _, valid := range StatusStates {
+		if state == valid {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *OutCommand) fileContents(path string) (string, error) {
 	contents, err := ioutil.ReadFile(path)
 	if err != nil {
@@ -1,6 +1,7 @@
 package resource_test
 
 import (
+	"context"
 	"io/ioutil"
 	"time"
 
@@ -27,8 +28,8 @@ var _ = Describe("Status Out Command", func() {
 		command = resource.NewOutCommand(githubClient, ioutil.Discard)
 	})
 
-	buildDeployment := func(id int64, env string, task string) *github.Deployment {
-		return &github.Deployment{
+	buildDeployment := func(id int64, env string, task string) *resource.Deployment {
+		return &resource.Deployment{Deployment: &github.Deployment{
 			ID:          github.Int64(id),
 			Environment: github.String(env),
 			Task:        github.String(task),
@@ -38,15 +39,15 @@ var _ = Describe("Status Out Command", func() {
 			Creator: &github.User{
 				Login: github.String("Something"),
 			},
-			CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
-		}
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 15, 15, 15, 0, time.UTC)},
+		}}
 	}
 
 	buildDeploymentStatus := func(ID int64, state string) *github.DeploymentStatus {
 		return &github.DeploymentStatus{
 			ID:        github.Int64(ID),
 			State:     github.String(state),
-			CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 20, 20, 20, 0, time.UTC)},
+			CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 20, 20, 20, 0, time.UTC)},
 		}
 	}
 
@@ -60,7 +61,7 @@ var _ = Describe("Status Out Command", func() {
 			githubClient.CreateDeploymentStatusReturns(&github.DeploymentStatus{
 				ID:        github.Int64(12),
 				State:     github.String("success"),
-				CreatedAt: &github.Timestamp{time.Date(2016, 01, 20, 20, 20, 20, 0, time.UTC)},
+				CreatedAt: &github.Timestamp{Time: time.Date(2016, 01, 20, 20, 20, 20, 0, time.UTC)},
 			}, nil)
 		})
 		Context("with strings in params", func() {
@@ -74,18 +75,18 @@ var _ = Describe("Status Out Command", func() {
 			})
 
 			It("creates a new status", func() {
-				_, err := command.Run(sourcesDir, request)
+				_, err := command.Run(context.Background(), sourcesDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(1))
-				id, status := githubClient.CreateDeploymentStatusArgsForCall(0)
+				_, id, status := githubClient.CreateDeploymentStatusArgsForCall(0)
 
 				Ω(id).Should(Equal(*github.Int64(1234)))
 				Ω(status.State).Should(Equal(github.String("success")))
 			})
 
 			It("returns some metadata", func() {
-				outResponse, err := command.Run(sourcesDir, request)
+				outResponse, err := command.Run(context.Background(), sourcesDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(outResponse.Metadata).Should(ConsistOf(
@@ -105,7 +106,7 @@ var _ = Describe("Status Out Command", func() {
 			})
 
 			It("returns the version number of the deployment, not the status", func() {
-				outResponse, err := command.Run(sourcesDir, request)
+				outResponse, err := command.Run(context.Background(), sourcesDir, request)
 				Ω(err).ShouldNot(HaveOccurred())
 
 				Ω(outResponse.Version).Should(Equal(
@@ -117,6 +118,48 @@ var _ = Describe("Status Out Command", func() {
 			})
 		})
 
+		Context("with the other status fields", func() {
+			BeforeEach(func() {
+				request = resource.OutRequest{
+					Params: resource.OutParams{
+						ID:             github.String("1234"),
+						State:          github.String("in_progress"),
+						Environment:    github.String("staging"),
+						EnvironmentURL: github.String("https://staging.example.com"),
+						LogURL:         github.String("https://ci.example.com/builds/1"),
+						TargetURL:      github.String("https://ci.example.com/builds/1/target"),
+						AutoInactive:   github.Bool(false),
+					},
+				}
+			})
+
+			It("sends them with the new status", func() {
+				_, err := command.Run(context.Background(), sourcesDir, request)
+				Ω(err).ShouldNot(HaveOccurred())
+
+				_, _, status := githubClient.CreateDeploymentStatusArgsForCall(0)
+				Ω(status).Should(Equal(&resource.DeploymentStatusRequest{
+					State:          github.String("in_progress"),
+					Environment:    github.String("staging"),
+					EnvironmentURL: github.String("https://staging.example.com"),
+					LogURL:         github.String("https://ci.example.com/builds/1"),
+					TargetURL:      github.String("https://ci.example.com/builds/1/target"),
+					AutoInactive:   github.Bool(false),
+				}))
+			})
+		})
+
+		It("rejects unknown states", func() {
+			_, err := command.Run(context.Background(), sourcesDir, resource.OutRequest{
+				Params: resource.OutParams{
+					ID:    github.String("1234"),
+					State: github.String("done"),
+				},
+			})
+			Ω(err).Should(MatchError(`unknown state "done", must be one of error, failure, inactive, in_progress, queued, pending, success`))
+			Ω(githubClient.CreateDeploymentStatusCallCount()).Should(Equal(0))
+		})
+
 		Context("when a required param is missing", func() {
 			BeforeEach(func() {
 				request = resource.OutRequest{
@@ -125,14 +168,14 @@ var _ = Describe("Status Out Command", func() {
 			})
 
 			It("id missing returns appropriate error", func() {
-				_, err := command.Run(sourcesDir, resource.OutRequest{
+				_, err := command.Run(context.Background(), sourcesDir, resource.OutRequest{
 					Params: resource.OutParams{},
 				})
 				Ω(err).Should(MatchError("id is a required parameter"))
 			})
 
 			It("state missing returns appropriate error", func() {
-				_, err := command.Run(sourcesDir, resource.OutRequest{
+				_, err := command.Run(context.Background(), sourcesDir, resource.OutRequest{
 					Params: resource.OutParams{
 						ID: github.String("1"),
 					},
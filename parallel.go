@@ -0,0 +1,37 @@
+package resource
+
+import "sync"
+
+// inParallel calls fn for every index in [0, n) using at most workers
+// goroutines. It waits for every call to finish and returns the first error.
+func inParallel(n, workers int, fn func(i int) error) error {
+	if workers <= 0 {
+		workers = 1
+	}
+
+	indexes := make(chan int)
+	errs := make(chan error, n)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if err := fn(i); err != nil {
+					errs <- err
+				}
+			}
+		}()
+	}
+
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+
+	wg.Wait()
+	close(errs)
+
+	return <-errs
+}
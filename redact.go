@@ -0,0 +1,61 @@
+package resource
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redacted = "<redacted>"
+
+var (
+	secretsLock sync.RWMutex
+	secrets     []string
+
+	secretPatterns = []*regexp.Regexp{
+		// Authorization headers, however they are printed.
+		regexp.MustCompile(`(?i)(authorization["']?\s*[:=]\s*\[?["']?)(?:bearer |token |basic )?[^\s"'\],<]+`),
+		// Tokens in JSON bodies, such as installation tokens.
+		regexp.MustCompile(`("(?:token|access_token|private_key|client_key)"\s*:\s*")[^"]*`),
+		// Tokens passed in query strings.
+		regexp.MustCompile(`((?:access_token|token)=)[^&\s]+`),
+		// GitHub tokens and JWTs wherever they appear.
+		regexp.MustCompile(`()\bgh[pousr]_[A-Za-z0-9_]{20,}`),
+		regexp.MustCompile(`()\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	}
+)
+
+// addSecret registers a value, such as a token or key from the source, that
+// must never be printed.
+func addSecret(secret string) {
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return
+	}
+
+	secretsLock.Lock()
+	defer secretsLock.Unlock()
+
+	secrets = append(secrets, secret)
+	// Replace longer secrets first, so one containing another is fully hidden.
+	sort.Slice(secrets, func(i, j int) bool {
+		return len(secrets[i]) > len(secrets[j])
+	})
+}
+
+// Redact hides credentials in anything that is about to be printed, both the
+// registered secrets and anything that looks like a token.
+func Redact(s string) string {
+	secretsLock.RLock()
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, redacted, -1)
+	}
+	secretsLock.RUnlock()
+
+	for _, pattern := range secretPatterns {
+		s = pattern.ReplaceAllString(s, "${1}"+redacted)
+	}
+
+	return s
+}
@@ -1,11 +1,15 @@
 package resource_test
 
 import (
+	"encoding/json"
+
 	"github.com/google/go-github/v28/github"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	"testing"
+
+	resource "github.com/ahume/github-deployment-resource"
 )
 
 func TestGithubDeploymentResource(t *testing.T) {
@@ -13,15 +17,46 @@ func TestGithubDeploymentResource(t *testing.T) {
 	RunSpecs(t, "GithubDeploymentResource Suite")
 }
 
-func newDeployment(id int64) *github.Deployment {
-	return &github.Deployment{
+// deploymentOption sets a field of a deployment made by newDeployment.
+type deploymentOption func(*resource.Deployment)
+
+func newDeployment(id int64, options ...deploymentOption) *resource.Deployment {
+	deployment := &resource.Deployment{Deployment: &github.Deployment{
 		ID: github.Int64(id),
+	}}
+	for _, option := range options {
+		option(deployment)
 	}
+	return deployment
+}
+
+func newDeploymentWithEnvironment(id int64, env string) *resource.Deployment {
+	return newDeployment(id, withEnvironment(env))
+}
+
+func withEnvironment(env string) deploymentOption {
+	return func(d *resource.Deployment) { d.Environment = github.String(env) }
+}
+
+func withRef(ref string) deploymentOption {
+	return func(d *resource.Deployment) { d.Ref = github.String(ref) }
+}
+
+func withTask(task string) deploymentOption {
+	return func(d *resource.Deployment) { d.Task = github.String(task) }
+}
+
+func withCreator(login string) deploymentOption {
+	return func(d *resource.Deployment) { d.Creator = &github.User{Login: github.String(login)} }
+}
+
+func withPayload(payload string) deploymentOption {
+	return func(d *resource.Deployment) { d.Payload = json.RawMessage(payload) }
 }
 
-func newDeploymentWithEnvironment(id int64, env string) *github.Deployment {
-	return &github.Deployment{
-		ID:          github.Int64(id),
-		Environment: &env,
+func withEnvironmentFlags(transient, production *bool) deploymentOption {
+	return func(d *resource.Deployment) {
+		d.TransientEnvironment = transient
+		d.ProductionEnvironment = production
 	}
 }
@@ -2,10 +2,15 @@ package resource
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/go-github/v28/github"
 	"github.com/peterbourgon/mergemap"
@@ -17,11 +22,85 @@ type Source struct {
 	AccessToken  string   `json:"access_token"`
 	GitHubAPIURL string   `json:"github_api_url"`
 	Environments []string `json:"environments"`
+	PageSize     int      `json:"page_size"`
+	MaxPages     int      `json:"max_pages"`
+
+	VersionMode   string   `json:"version_mode"`
+	TrackStatuses bool     `json:"track_statuses"`
+	StatusFilter  []string `json:"status_filter"`
+	Concurrency   int      `json:"concurrency"`
+
+	RefFilter         *Filter `json:"ref_filter"`
+	TaskFilter        *Filter `json:"task_filter"`
+	EnvironmentFilter *Filter `json:"environment_filter"`
+	CreatorFilter     *Filter `json:"creator_filter"`
+
+	ProductionOnly   bool `json:"production_only"`
+	ExcludeTransient bool `json:"exclude_transient"`
+
+	PayloadFilter   []PayloadFilter `json:"payload_filter"`
+	OnlyOwnPipeline bool            `json:"only_own_pipeline"`
+	PipelineName    string          `json:"pipeline_name"`
+	TeamName        string          `json:"team_name"`
+
+	AppID          int64  `json:"app_id"`
+	PrivateKey     string `json:"private_key"`
+	InstallationID int64  `json:"installation_id"`
+
+	MaxRetries       *int      `json:"max_retries"`
+	MaxRateLimitWait *Duration `json:"max_rate_limit_wait"`
+	RequestTimeout   *Duration `json:"request_timeout"`
+	Timeout          *Duration `json:"timeout"`
+
+	CACert             string `json:"ca_cert"`
+	ClientCert         string `json:"client_cert"`
+	ClientKey          string `json:"client_key"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify"`
+	HTTPProxy          string `json:"http_proxy"`
+
+	CacheDir string `json:"cache_dir"`
+
+	Debug bool `json:"debug"`
+}
+
+// Duration is a time.Duration that is configured as a string such as "90s"
+// or "5m".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("durations must be strings such as \"30s\": %s", string(b))
+	}
+
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	d.Duration = duration
+	return nil
 }
 
 type Version struct {
 	ID       string `json:"id"`
 	Statuses string `json:"status"`
+
+	// Deleted is "true" for the version put by type=delete, so that the
+	// implicit get does not look for the deployment that no longer exists.
+	Deleted string `json:"deleted,omitempty"`
+
+	// Cleanup is "true" for the version put by type=cleanup, whose ID is the
+	// newest deployment it looked at. The implicit get does not fetch it, as
+	// it may have been deleted.
+	Cleanup string `json:"cleanup,omitempty"`
+
+	// Environment and EnvironmentID are set instead of ID for the version put
+	// by type=environment, which does not create a deployment.
+	Environment   string `json:"environment,omitempty"`
+	EnvironmentID string `json:"environment_id,omitempty"`
 }
 
 type CheckRequest struct {
@@ -61,6 +140,43 @@ type OutParams struct {
 	Payload     *map[string]interface{}
 	PayloadPath *string `json:"payload_path"`
 
+	// RefFromRepo is the path of a git repository to read ref from, instead
+	// of giving ref itself.
+	RefFromRepo *string `json:"ref_from_repo"`
+	// AllowDirty lets ref_from_repo read a repository with uncommitted
+	// changes to tracked files.
+	AllowDirty bool `json:"allow_dirty"`
+
+	// RequiredContexts are the commit status contexts that must pass before
+	// GitHub creates a deployment. DefaultRequiredContexts is set instead when
+	// required_contexts is "default", so that GitHub checks every context.
+	RequiredContexts        *[]string
+	DefaultRequiredContexts bool
+
+	TransientEnvironment  *bool
+	ProductionEnvironment *bool
+
+	// InitialStatus is the state of a status created along with a
+	// deployment, with its own description and log URL.
+	InitialStatus      *string
+	InitialDescription *string
+	InitialLogURL      *string
+
+	EnvironmentURL *string
+	LogURL         *string
+	TargetURL      *string
+	AutoInactive   *bool
+
+	KeepLast      *int      `json:"keep_last"`
+	OlderThan     *Duration `json:"older_than"`
+	TransientOnly bool      `json:"transient_only"`
+	Delete        bool      `json:"delete"`
+	DryRun        bool      `json:"dry_run"`
+
+	WaitTimer          *int                   `json:"wait_timer"`
+	Reviewers          []*EnvironmentReviewer `json:"reviewers"`
+	DeploymentBranches *DeploymentBranches    `json:"deployment_branches"`
+
 	RawID          json.RawMessage `json:"id"`
 	RawState       json.RawMessage `json:"state"`
 	RawRef         json.RawMessage `json:"ref"`
@@ -69,58 +185,243 @@ type OutParams struct {
 	RawDescription json.RawMessage `json:"description"`
 	RawAutoMerge   json.RawMessage `json:"auto_merge"`
 	RawPayload     json.RawMessage `json:"payload"`
+
+	RawEnvironmentURL json.RawMessage `json:"environment_url"`
+	RawLogURL         json.RawMessage `json:"log_url"`
+	RawTargetURL      json.RawMessage `json:"target_url"`
+	RawAutoInactive   json.RawMessage `json:"auto_inactive"`
+
+	RawRequiredContexts json.RawMessage `json:"required_contexts"`
+
+	RawTransientEnvironment  json.RawMessage `json:"transient_environment"`
+	RawProductionEnvironment json.RawMessage `json:"production_environment"`
+
+	RawInitialStatus      json.RawMessage `json:"initial_status"`
+	RawInitialDescription json.RawMessage `json:"initial_description"`
+	RawInitialLogURL      json.RawMessage `json:"initial_log_url"`
+
+	// keys are the names of the params that were set, for validation.
+	keys []string
+
+	// shortRef is the abbreviated commit read from ref_from_repo.
+	shortRef string
 }
 
 // Used to avoid recursion in UnmarshalJSON below.
 type outParams OutParams
 
-func (p *OutParams) UnmarshalJSON(b []byte) (err error) {
+// UnmarshalJSON decodes the params and records which were set. Params that
+// can be read from files are only read by Resolve.
+func (p *OutParams) UnmarshalJSON(b []byte) error {
 	j := outParams{
 		Type: github.String("status"),
 	}
 
-	if err = json.Unmarshal(b, &j); err == nil {
-		*p = OutParams(j)
-		if p.RawID != nil {
-			p.ID = github.String(getStringOrStringFromFile(p.RawID))
+	if err := json.Unmarshal(b, &j); err != nil {
+		return err
+	}
+	*p = OutParams(j)
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	for key := range raw {
+		p.keys = append(p.keys, key)
+	}
+	sort.Strings(p.keys)
+
+	return nil
+}
+
+// Resolve reads the params that are either given directly or read from a
+// file, resolving file paths relative to sourceDir, renders their templates
+// and builds the payload.
+func (p *OutParams) Resolve(sourceDir string) error {
+	stringParams := []struct {
+		name  string
+		raw   json.RawMessage
+		value **string
+	}{
+		{"id", p.RawID, &p.ID},
+		{"state", p.RawState, &p.State},
+		{"ref", p.RawRef, &p.Ref},
+		{"task", p.RawTask, &p.Task},
+		{"environment", p.RawEnvironment, &p.Environment},
+		{"description", p.RawDescription, &p.Description},
+		{"environment_url", p.RawEnvironmentURL, &p.EnvironmentURL},
+		{"log_url", p.RawLogURL, &p.LogURL},
+		{"target_url", p.RawTargetURL, &p.TargetURL},
+		{"initial_status", p.RawInitialStatus, &p.InitialStatus},
+		{"initial_description", p.RawInitialDescription, &p.InitialDescription},
+		{"initial_log_url", p.RawInitialLogURL, &p.InitialLogURL},
+	}
+
+	for _, param := range stringParams {
+		if !isSet(param.raw) {
+			continue
 		}
 
-		if p.RawState != nil {
-			p.State = github.String(getStringOrStringFromFile(p.RawState))
+		value, err := getStringOrStringFromFile(sourceDir, param.name, param.raw)
+		if err != nil {
+			return err
 		}
+		*param.value = &value
+	}
 
-		if p.RawRef != nil {
-			p.Ref = github.String(getStringOrStringFromFile(p.RawRef))
+	boolParams := []struct {
+		name  string
+		raw   json.RawMessage
+		value **bool
+	}{
+		{"auto_merge", p.RawAutoMerge, &p.AutoMerge},
+		{"auto_inactive", p.RawAutoInactive, &p.AutoInactive},
+		{"transient_environment", p.RawTransientEnvironment, &p.TransientEnvironment},
+		{"production_environment", p.RawProductionEnvironment, &p.ProductionEnvironment},
+	}
+
+	for _, param := range boolParams {
+		if !isSet(param.raw) {
+			continue
+		}
+
+		value, err := getBoolOrBoolFromFile(sourceDir, param.name, param.raw)
+		if err != nil {
+			return err
 		}
+		*param.value = value
+	}
 
-		if p.RawTask != nil {
-			p.Task = github.String(getStringOrStringFromFile(p.RawTask))
+	if p.RefFromRepo != nil && p.Ref == nil {
+		ref, err := readRepoRef(filepath.Join(sourceDir, *p.RefFromRepo), p.AllowDirty)
+		if err != nil {
+			return &ReadParamError{Param: "ref_from_repo", Path: *p.RefFromRepo, Err: err}
 		}
+		p.Ref = &ref.Ref
+		p.shortRef = ref.ShortSHA
+	}
+
+	if err := p.resolveRequiredContexts(sourceDir); err != nil {
+		return err
+	}
+
+	if err := p.renderTemplates(sourceDir); err != nil {
+		return err
+	}
+
+	return p.resolvePayload(sourceDir)
+}
+
+// resolveRequiredContexts reads required_contexts, which is either a list,
+// "default", or read from a file as a JSON list or one context per line.
+func (p *OutParams) resolveRequiredContexts(sourceDir string) error {
+	if !isSet(p.RawRequiredContexts) {
+		return nil
+	}
+
+	var contexts []string
+	if err := json.Unmarshal(p.RawRequiredContexts, &contexts); err == nil {
+		p.RequiredContexts = &contexts
+		return nil
+	}
 
-		if p.RawEnvironment != nil {
-			p.Environment = github.String(getStringOrStringFromFile(p.RawEnvironment))
+	var value string
+	if err := json.Unmarshal(p.RawRequiredContexts, &value); err == nil {
+		if value != "default" {
+			return &ReadParamError{Param: "required_contexts", Err: errors.New(`must be a list, "default" or {file: path}`)}
 		}
+		p.DefaultRequiredContexts = true
+		return nil
+	}
+
+	value, err := getStringOrStringFromFile(sourceDir, "required_contexts", p.RawRequiredContexts)
+	if err != nil {
+		return err
+	}
 
-		if p.RawDescription != nil {
-			p.Description = github.String(getStringOrStringFromFile(p.RawDescription))
+	contexts = []string{}
+	if err := json.Unmarshal([]byte(value), &contexts); err != nil {
+		contexts = []string{}
+		for _, line := range strings.Split(value, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				contexts = append(contexts, line)
+			}
 		}
+	}
 
-		var payload map[string]interface{}
-		json.Unmarshal(p.RawPayload, &payload)
+	p.RequiredContexts = &contexts
+	return nil
+}
 
-		if p.PayloadPath != nil {
-			stringFromFile := fileContents(*p.PayloadPath)
-			var payloadFromFile map[string]interface{}
-			json.Unmarshal([]byte(stringFromFile), &payloadFromFile)
+// resolvePayload merges payload into the JSON read from payload_path, with
+// payload taking precedence.
+func (p *OutParams) resolvePayload(sourceDir string) error {
+	if !isSet(p.RawPayload) && p.PayloadPath == nil {
+		return nil
+	}
 
-			payload = mergemap.Merge(payloadFromFile, payload)
+	payload := map[string]interface{}{}
+	if isSet(p.RawPayload) {
+		if err := json.Unmarshal(p.RawPayload, &payload); err != nil {
+			return &ReadParamError{Param: "payload", Err: err}
 		}
+	}
+
+	if p.PayloadPath != nil {
+		contents, err := fileContents(sourceDir, *p.PayloadPath)
+		if err != nil {
+			return &ReadParamError{Param: "payload_path", Path: *p.PayloadPath, Err: err}
+		}
+
+		payloadFromFile := map[string]interface{}{}
+		if err := json.Unmarshal([]byte(contents), &payloadFromFile); err != nil {
+			return &ReadParamError{Param: "payload_path", Path: *p.PayloadPath, Err: err}
+		}
+
+		payload = mergemap.Merge(payloadFromFile, payload)
+	}
 
-		p.Payload = &payload
+	p.Payload = &payload
+	return nil
+}
+
+// ReadParamError is returned when a param cannot be read. Path is set when
+// the param was read from a file.
+type ReadParamError struct {
+	Param string
+	Path  string
+	Err   error
+}
 
-		return
+func (e *ReadParamError) Error() string {
+	if e.Path != "" {
+		return fmt.Sprintf("reading %s from %s: %s", e.Param, e.Path, e.Err)
 	}
-	return
+	return fmt.Sprintf("reading %s: %s", e.Param, e.Err)
+}
+
+// DeploymentBranches restricts which branches can deploy to an environment.
+// It is configured either as "protected", to only allow protected branches,
+// or as a list of branch name patterns.
+type DeploymentBranches struct {
+	Protected bool
+	Patterns  []string
+}
+
+func (d *DeploymentBranches) UnmarshalJSON(b []byte) error {
+	var protected string
+	if err := json.Unmarshal(b, &protected); err == nil {
+		if protected != "protected" {
+			return fmt.Errorf("deployment_branches must be \"protected\" or a list of branch patterns, not %q", protected)
+		}
+		d.Protected = true
+		return nil
+	}
+
+	if err := json.Unmarshal(b, &d.Patterns); err != nil {
+		return fmt.Errorf("deployment_branches must be \"protected\" or a list of branch patterns: %s", string(b))
+	}
+	return nil
 }
 
 type MetadataPair struct {
@@ -140,27 +441,102 @@ func NewOutRequest() OutRequest {
 	return OutRequest{}
 }
 
-func getStringOrStringFromFile(field json.RawMessage) string {
+var errNotStringOrFile = errors.New("must be a string, {file: path} or {env: VAR}")
+
+// getStringOrStringFromFile reads a param that is either a string or one of
+// {file: path}, which may have a json_path or yaml_path to read a single value
+// from the file, or {env: VAR}.
+func getStringOrStringFromFile(sourceDir, name string, field json.RawMessage) (string, error) {
 	var rawValue interface{}
-	if err := json.Unmarshal(field, &rawValue); err == nil {
-		switch rawValue := rawValue.(type) {
-		case string:
-			return rawValue
-		case map[string]interface{}:
-			return fileContents(rawValue["file"].(string))
-		default:
-			panic("Could not read string out of Params field")
+	if err := json.Unmarshal(field, &rawValue); err != nil {
+		return "", &ReadParamError{Param: name, Err: err}
+	}
+
+	switch rawValue := rawValue.(type) {
+	case string:
+		return rawValue, nil
+	case map[string]interface{}:
+		return getStringFromSource(sourceDir, name, rawValue)
+	default:
+		return "", &ReadParamError{Param: name, Err: errNotStringOrFile}
+	}
+}
+
+func getStringFromSource(sourceDir, name string, source map[string]interface{}) (string, error) {
+	if env, ok := source["env"].(string); ok {
+		value, found := os.LookupEnv(env)
+		if !found {
+			return "", &ReadParamError{Param: name, Err: fmt.Errorf("environment variable %s is not set", env)}
 		}
+		return value, nil
+	}
+
+	path, ok := source["file"].(string)
+	if !ok {
+		return "", &ReadParamError{Param: name, Err: errNotStringOrFile}
+	}
+
+	jsonPath, hasJSONPath := source["json_path"].(string)
+	yamlPath, hasYAMLPath := source["yaml_path"].(string)
+	if hasJSONPath && hasYAMLPath {
+		return "", &ReadParamError{Param: name, Path: path, Err: errors.New("only one of json_path or yaml_path can be set")}
+	}
+
+	if !hasJSONPath && !hasYAMLPath {
+		contents, err := fileContents(sourceDir, path)
+		if err != nil {
+			return "", &ReadParamError{Param: name, Path: path, Err: err}
+		}
+		return contents, nil
+	}
+
+	contents, err := ioutil.ReadFile(filepath.Join(sourceDir, path))
+	if err != nil {
+		return "", &ReadParamError{Param: name, Path: path, Err: err}
+	}
+
+	format, valuePath := "json", jsonPath
+	if hasYAMLPath {
+		format, valuePath = "yaml", yamlPath
 	}
-	return ""
+
+	value, err := extractPath(contents, format, valuePath)
+	if err != nil {
+		return "", &ReadParamError{Param: name, Path: path, Err: err}
+	}
+	return value, nil
+}
+
+// getBoolOrBoolFromFile reads a boolean that is either given directly or as a
+// string, which may itself be read from a file.
+func getBoolOrBoolFromFile(sourceDir, name string, field json.RawMessage) (*bool, error) {
+	var value bool
+	if err := json.Unmarshal(field, &value); err == nil {
+		return &value, nil
+	}
+
+	s, err := getStringOrStringFromFile(sourceDir, name, field)
+	if err != nil {
+		return nil, err
+	}
+
+	value, err = strconv.ParseBool(s)
+	if err != nil {
+		return nil, &ReadParamError{Param: name, Err: fmt.Errorf("%q is not true or false", s)}
+	}
+	return &value, nil
 }
 
-func fileContents(path string) string {
-	sourceDir := os.Args[1]
+func fileContents(sourceDir, path string) (string, error) {
 	contents, err := ioutil.ReadFile(filepath.Join(sourceDir, path))
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
-	return strings.TrimSpace(string(contents))
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// isSet reports whether a raw param was given a value other than null.
+func isSet(raw json.RawMessage) bool {
+	return len(raw) > 0 && string(raw) != "null"
 }
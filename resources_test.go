@@ -3,6 +3,7 @@ package resource_test
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -10,6 +11,8 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	"github.com/google/go-github/v28/github"
+
 	resource "github.com/ahume/github-deployment-resource"
 )
 
@@ -30,15 +33,19 @@ var _ = Describe("Resources", func() {
 
 		sourceDir, err = ioutil.TempDir("", "github-deployment")
 		Ω(err).ShouldNot(HaveOccurred())
-
-		// Um.
-		os.Args = []string{"out", sourceDir}
 	})
 
 	AfterEach(func() {
 		Ω(os.RemoveAll(sourceDir)).Should(Succeed())
 	})
 
+	decode := func(r io.Reader) error {
+		if err := json.NewDecoder(r).Decode(&p); err != nil {
+			return err
+		}
+		return p.Params.Resolve(sourceDir)
+	}
+
 	Context("Params is unmarshalled", func() {
 
 		BeforeEach(func() {
@@ -49,7 +56,7 @@ var _ = Describe("Resources", func() {
 			r := bytes.NewReader([]byte(`{
 				"params": {}
 				}`))
-			_ = json.NewDecoder(r).Decode(&p)
+			_ = decode(r)
 			Ω(*p.Params.Type).Should(Equal("status"))
 		})
 
@@ -64,7 +71,7 @@ var _ = Describe("Resources", func() {
 					"description": "description-string"
 					}
 				}`))
-			err := json.NewDecoder(r).Decode(&p)
+			err := decode(r)
 
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(*p.Params.Type).Should(Equal("deployment"))
@@ -75,6 +82,19 @@ var _ = Describe("Resources", func() {
 			Ω(*p.Params.Description).Should(Equal("description-string"))
 		})
 
+		It("reads deployment_branches as protected or a list of patterns", func() {
+			r := bytes.NewReader([]byte(`{"params": {"deployment_branches": "protected"}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.DeploymentBranches).Should(Equal(&resource.DeploymentBranches{Protected: true}))
+
+			r = bytes.NewReader([]byte(`{"params": {"deployment_branches": ["main", "release/*"]}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.DeploymentBranches.Patterns).Should(Equal([]string{"main", "release/*"}))
+
+			r = bytes.NewReader([]byte(`{"params": {"deployment_branches": "all"}}`))
+			Ω(decode(r)).Should(MatchError(ContainSubstring("deployment_branches must be")))
+		})
+
 		It("gets values from files", func() {
 			idPath := filepath.Join(sourceDir, "id")
 			refPath := filepath.Join(sourceDir, "ref")
@@ -113,7 +133,7 @@ var _ = Describe("Resources", func() {
 					}
 				}
 			}`))
-			err := json.NewDecoder(r).Decode(&p)
+			err := decode(r)
 
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(*p.Params.Type).Should(Equal("deployment"))
@@ -125,6 +145,161 @@ var _ = Describe("Resources", func() {
 			Ω(*p.Params.Description).Should(Equal("description-from-file"))
 		})
 
+		It("gets status urls from files", func() {
+			file(filepath.Join(sourceDir, "url"), "https://staging.example.com")
+			file(filepath.Join(sourceDir, "auto_inactive"), "false")
+
+			r := bytes.NewReader([]byte(`{
+				"params": {
+					"environment_url": {"file": "url"},
+					"log_url": "https://ci.example.com/builds/1",
+					"target_url": "https://ci.example.com/builds/1/target",
+					"auto_inactive": {"file": "auto_inactive"}
+				}
+			}`))
+			err := decode(r)
+
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(*p.Params.EnvironmentURL).Should(Equal("https://staging.example.com"))
+			Ω(*p.Params.LogURL).Should(Equal("https://ci.example.com/builds/1"))
+			Ω(*p.Params.TargetURL).Should(Equal("https://ci.example.com/builds/1/target"))
+			Ω(*p.Params.AutoInactive).Should(BeFalse())
+		})
+
+		It("reads auto_merge", func() {
+			r := bytes.NewReader([]byte(`{"params": {"type": "deployment", "auto_merge": false}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.AutoMerge).Should(Equal(github.Bool(false)))
+		})
+
+		It("reads auto_inactive as a boolean", func() {
+			r := bytes.NewReader([]byte(`{"params": {"auto_inactive": true}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(*p.Params.AutoInactive).Should(BeTrue())
+
+			r = bytes.NewReader([]byte(`{"params": {"auto_inactive": "maybe"}}`))
+			Ω(decode(r)).Should(MatchError(ContainSubstring("auto_inactive")))
+		})
+
+		It("names the param and file when a file is missing", func() {
+			r := bytes.NewReader([]byte(`{"params": {"id": {"file": "missing/id"}}}`))
+			err := decode(r)
+
+			Ω(err).Should(BeAssignableToTypeOf(&resource.ReadParamError{}))
+			readErr := err.(*resource.ReadParamError)
+			Ω(readErr.Param).Should(Equal("id"))
+			Ω(readErr.Path).Should(Equal("missing/id"))
+			Ω(err.Error()).Should(HavePrefix("reading id from missing/id: "))
+		})
+
+		It("rejects values that are neither strings nor files", func() {
+			r := bytes.NewReader([]byte(`{"params": {"description": 42}}`))
+			Ω(decode(r)).Should(MatchError("reading description: must be a string, {file: path} or {env: VAR}"))
+
+			r = bytes.NewReader([]byte(`{"params": {"description": {"path": "description"}}}`))
+			Ω(decode(r)).Should(MatchError("reading description: must be a string, {file: path} or {env: VAR}"))
+		})
+
+		It("reports payloads that are not valid JSON objects", func() {
+			file(filepath.Join(sourceDir, "payload"), `not json`)
+
+			r := bytes.NewReader([]byte(`{"params": {"type": "deployment", "payload_path": "payload"}}`))
+			Ω(decode(r)).Should(MatchError(HavePrefix("reading payload_path from payload: ")))
+
+			r = bytes.NewReader([]byte(`{"params": {"type": "deployment", "payload": "not an object"}}`))
+			Ω(decode(r)).Should(MatchError(HavePrefix("reading payload: ")))
+		})
+
+		It("leaves the payload unset when neither payload nor payload_path is given", func() {
+			r := bytes.NewReader([]byte(`{"params": {"type": "deployment"}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.Payload).Should(BeNil())
+		})
+
+		Context("reading values from structured files", func() {
+			BeforeEach(func() {
+				file(filepath.Join(sourceDir, "report.json"), `{"deploy": {"version": "1.2.3", "build": 42, "hosts": ["a", "b"]}}`)
+				file(filepath.Join(sourceDir, "report.yml"), "deploy:\n  version: 4.5.6\n  hosts:\n    - c\n    - d\n")
+			})
+
+			It("reads a single value with json_path", func() {
+				r := bytes.NewReader([]byte(`{"params": {
+					"ref": {"file": "report.json", "json_path": ".deploy.version"},
+					"task": {"file": "report.json", "json_path": "deploy.build"},
+					"description": {"file": "report.json", "json_path": ".deploy.hosts"}
+				}}`))
+				Ω(decode(r)).Should(Succeed())
+				Ω(*p.Params.Ref).Should(Equal("1.2.3"))
+				Ω(*p.Params.Task).Should(Equal("42"))
+				Ω(*p.Params.Description).Should(Equal(`["a","b"]`))
+			})
+
+			It("reads a single value with yaml_path", func() {
+				r := bytes.NewReader([]byte(`{"params": {
+					"ref": {"file": "report.yml", "yaml_path": ".deploy.version"},
+					"task": {"file": "report.yml", "yaml_path": ".deploy.hosts.1"}
+				}}`))
+				Ω(decode(r)).Should(Succeed())
+				Ω(*p.Params.Ref).Should(Equal("4.5.6"))
+				Ω(*p.Params.Task).Should(Equal("d"))
+			})
+
+			It("names the param, file and path when the path is missing", func() {
+				r := bytes.NewReader([]byte(`{"params": {"ref": {"file": "report.json", "json_path": ".deploy.sha"}}}`))
+				Ω(decode(r)).Should(MatchError("reading ref from report.json: json_path .deploy.sha not found"))
+			})
+
+			It("rejects both json_path and yaml_path", func() {
+				r := bytes.NewReader([]byte(`{"params": {"ref": {"file": "report.json", "json_path": ".a", "yaml_path": ".a"}}}`))
+				Ω(decode(r)).Should(MatchError("reading ref from report.json: only one of json_path or yaml_path can be set"))
+			})
+		})
+
+		Context("reading values from environment variables", func() {
+			BeforeEach(func() {
+				os.Setenv("GITHUB_DEPLOYMENT_TEST_REF", "from-env")
+			})
+
+			AfterEach(func() {
+				os.Unsetenv("GITHUB_DEPLOYMENT_TEST_REF")
+			})
+
+			It("reads the variable", func() {
+				r := bytes.NewReader([]byte(`{"params": {"ref": {"env": "GITHUB_DEPLOYMENT_TEST_REF"}}}`))
+				Ω(decode(r)).Should(Succeed())
+				Ω(*p.Params.Ref).Should(Equal("from-env"))
+			})
+
+			It("fails when the variable is not set", func() {
+				r := bytes.NewReader([]byte(`{"params": {"ref": {"env": "GITHUB_DEPLOYMENT_TEST_MISSING"}}}`))
+				Ω(decode(r)).Should(MatchError("reading ref: environment variable GITHUB_DEPLOYMENT_TEST_MISSING is not set"))
+			})
+		})
+
+		It("reads required_contexts as a list, default or from a file", func() {
+			r := bytes.NewReader([]byte(`{"params": {"type": "deployment", "required_contexts": ["ci/build"]}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.RequiredContexts).Should(Equal(&[]string{"ci/build"}))
+
+			r = bytes.NewReader([]byte(`{"params": {"type": "deployment", "required_contexts": "default"}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.DefaultRequiredContexts).Should(BeTrue())
+			Ω(p.Params.RequiredContexts).Should(BeNil())
+
+			file(filepath.Join(sourceDir, "contexts"), "ci/build\n\nci/lint\n")
+			r = bytes.NewReader([]byte(`{"params": {"type": "deployment", "required_contexts": {"file": "contexts"}}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.RequiredContexts).Should(Equal(&[]string{"ci/build", "ci/lint"}))
+
+			file(filepath.Join(sourceDir, "contexts.json"), `["ci/build", "ci/security"]`)
+			r = bytes.NewReader([]byte(`{"params": {"type": "deployment", "required_contexts": {"file": "contexts.json"}}}`))
+			Ω(decode(r)).Should(Succeed())
+			Ω(p.Params.RequiredContexts).Should(Equal(&[]string{"ci/build", "ci/security"}))
+
+			r = bytes.NewReader([]byte(`{"params": {"type": "deployment", "required_contexts": "ci/build"}}`))
+			Ω(decode(r)).Should(MatchError(`reading required_contexts: must be a list, "default" or {file: path}`))
+		})
+
 		It("gets raw payload", func() {
 			r := bytes.NewReader([]byte(`{
 				"params": {
@@ -135,7 +310,7 @@ var _ = Describe("Resources", func() {
 					}
 				}
 			}`))
-			err := json.NewDecoder(r).Decode(&p)
+			err := decode(r)
 			payload := *p.Params.Payload
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(*p.Params.Type).Should(Equal("deployment"))
@@ -154,7 +329,7 @@ var _ = Describe("Resources", func() {
 					"payload_path": "payload"
 				}
 			}`))
-			err := json.NewDecoder(r).Decode(&p)
+			err := decode(r)
 			payload := *p.Params.Payload
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(*p.Params.Type).Should(Equal("deployment"))
@@ -0,0 +1,217 @@
+package resource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	defaultMaxRetries       = 3
+	defaultMaxRateLimitWait = time.Minute
+	defaultRequestTimeout   = 5 * time.Second
+
+	minRetryBackoff = 500 * time.Millisecond
+	maxRetryBackoff = 30 * time.Second
+)
+
+// retryTransport retries requests that fail for transient reasons, giving
+// each attempt its own timeout within that of the whole request. Server
+// errors and dropped connections are retried with exponential backoff and
+// jitter, but only for idempotent methods, as a POST may already have been
+// processed. Rate limited requests were rejected outright, so any method is
+// retried once the rate limit allows it, as long as that is within the wait
+// budget.
+type retryTransport struct {
+	base   http.RoundTripper
+	writer io.Writer
+
+	maxRetries       int
+	maxRateLimitWait time.Duration
+	requestTimeout   time.Duration
+
+	now func() time.Time
+}
+
+func newRetryTransport(base http.RoundTripper, writer io.Writer, source Source) *retryTransport {
+	maxRetries := defaultMaxRetries
+	if source.MaxRetries != nil {
+		maxRetries = *source.MaxRetries
+	}
+
+	maxRateLimitWait := defaultMaxRateLimitWait
+	if source.MaxRateLimitWait != nil {
+		maxRateLimitWait = source.MaxRateLimitWait.Duration
+	}
+
+	requestTimeout := defaultRequestTimeout
+	if source.RequestTimeout != nil {
+		requestTimeout = source.RequestTimeout.Duration
+	}
+
+	return &retryTransport{
+		base:             base,
+		writer:           writer,
+		maxRetries:       maxRetries,
+		maxRateLimitWait: maxRateLimitWait,
+		requestTimeout:   requestTimeout,
+		now:              time.Now,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := t.roundTripWithTimeout(req)
+
+		if attempt > t.maxRetries {
+			return res, err
+		}
+
+		wait, reason, retry := t.retryAfter(req, res, err, attempt)
+		if !retry {
+			return res, err
+		}
+
+		if deadline, ok := req.Context().Deadline(); ok && t.now().Add(wait).After(deadline) {
+			return res, err
+		}
+
+		if req.Body != nil {
+			if req.GetBody == nil {
+				return res, err
+			}
+
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return res, err
+			}
+			req.Body = body
+		}
+
+		if res != nil {
+			io.Copy(ioutil.Discard, res.Body)
+			res.Body.Close()
+		}
+
+		fmt.Fprintf(t.writer, "%s %s: %s, retrying in %s (attempt %d of %d)\n",
+			req.Method, req.URL.Path, reason, wait.Round(time.Millisecond), attempt+1, t.maxRetries+1)
+
+		if sleepErr := sleepContext(req.Context(), wait); sleepErr != nil {
+			return nil, sleepErr
+		}
+	}
+}
+
+// roundTripWithTimeout makes a single attempt at a request. The timeout
+// covers reading the response body too, so it is only cancelled once the body
+// has been closed.
+func (t *retryTransport) roundTripWithTimeout(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.requestTimeout)
+
+	res, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	res.Body = &cancelOnClose{ReadCloser: res.Body, cancel: cancel}
+	return res, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	defer c.cancel()
+	return c.ReadCloser.Close()
+}
+
+// retryAfter decides whether a request should be retried, and how long to
+// wait first.
+func (t *retryTransport) retryAfter(req *http.Request, res *http.Response, err error, attempt int) (time.Duration, string, bool) {
+	if err != nil {
+		if req.Context().Err() != nil || !idempotent(req.Method) || !transientNetworkError(err) {
+			return 0, "", false
+		}
+		return backoff(attempt), err.Error(), true
+	}
+
+	switch res.StatusCode {
+	case http.StatusForbidden, http.StatusTooManyRequests:
+		if retryAfter := res.Header.Get("Retry-After"); retryAfter != "" {
+			seconds, parseErr := strconv.Atoi(retryAfter)
+			if parseErr != nil {
+				return 0, "", false
+			}
+			wait := time.Duration(seconds) * time.Second
+			return wait, "secondary rate limit exceeded", wait <= t.maxRateLimitWait
+		}
+
+		if res.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, parseErr := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if parseErr != nil {
+				return 0, "", false
+			}
+			wait := time.Unix(reset, 0).Sub(t.now())
+			if wait < 0 {
+				wait = 0
+			}
+			return wait, "rate limit exceeded", wait <= t.maxRateLimitWait
+		}
+	case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		if idempotent(req.Method) {
+			return backoff(attempt), res.Status, true
+		}
+	}
+
+	return 0, "", false
+}
+
+// backoff doubles the wait for each attempt, up to a limit, with jitter so
+// that concurrent checks do not all retry at once.
+func backoff(attempt int) time.Duration {
+	wait := minRetryBackoff << uint(attempt-1)
+	if wait > maxRetryBackoff || wait <= 0 {
+		wait = maxRetryBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)))
+}
+
+func idempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+func transientNetworkError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
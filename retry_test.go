@@ -0,0 +1,154 @@
+package resource_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Retrying requests", func() {
+	var (
+		server *httptest.Server
+		source resource.Source
+		output *bytes.Buffer
+
+		requests  int
+		responses []func(w http.ResponseWriter)
+	)
+
+	ok := func(w http.ResponseWriter) {
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, `{"id":1}`)
+	}
+
+	status := func(code int) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.WriteHeader(code)
+			fmt.Fprint(w, `{"message":"nope"}`)
+		}
+	}
+
+	rateLimited := func(reset time.Time) func(w http.ResponseWriter) {
+		return func(w http.ResponseWriter) {
+			w.Header().Set("X-RateLimit-Limit", "5000")
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message":"API rate limit exceeded"}`)
+		}
+	}
+
+	secondaryRateLimited := func(w http.ResponseWriter) {
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message":"You have triggered an abuse detection mechanism"}`)
+	}
+
+	BeforeEach(func() {
+		requests = 0
+		responses = nil
+		output = &bytes.Buffer{}
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			respond := ok
+			if requests < len(responses) {
+				respond = responses[requests]
+			}
+			requests++
+			respond(w)
+		}))
+
+		source = resource.Source{
+			User:         "owner",
+			Repository:   "repo",
+			GitHubAPIURL: server.URL + "/",
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	getDeployment := func() error {
+		client, err := resource.NewGitHubClient(context.Background(), source, output)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = client.GetDeployment(context.Background(), 1)
+		return err
+	}
+
+	createDeployment := func() error {
+		client, err := resource.NewGitHubClient(context.Background(), source, output)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = client.CreateDeployment(context.Background(), &github.DeploymentRequest{Ref: github.String("master")})
+		return err
+	}
+
+	It("retries server errors with backoff and logs each attempt", func() {
+		responses = []func(w http.ResponseWriter){status(502), status(503)}
+
+		Ω(getDeployment()).Should(Succeed())
+		Ω(requests).Should(Equal(3))
+		Ω(output.String()).Should(ContainSubstring("GET /repos/owner/repo/deployments/1: 502 Bad Gateway, retrying in"))
+		Ω(output.String()).Should(ContainSubstring("(attempt 2 of 4)"))
+		Ω(output.String()).Should(ContainSubstring("(attempt 3 of 4)"))
+	})
+
+	It("gives up after the configured number of retries", func() {
+		source.MaxRetries = github.Int(1)
+		responses = []func(w http.ResponseWriter){status(500), status(500), status(500)}
+
+		Ω(getDeployment()).ShouldNot(Succeed())
+		Ω(requests).Should(Equal(2))
+	})
+
+	It("does not retry server errors for requests that are not idempotent", func() {
+		responses = []func(w http.ResponseWriter){status(502)}
+
+		Ω(createDeployment()).ShouldNot(Succeed())
+		Ω(requests).Should(Equal(1))
+	})
+
+	It("does not retry client errors", func() {
+		responses = []func(w http.ResponseWriter){status(404)}
+
+		Ω(getDeployment()).ShouldNot(Succeed())
+		Ω(requests).Should(Equal(1))
+	})
+
+	It("waits for the rate limit to reset", func() {
+		responses = []func(w http.ResponseWriter){rateLimited(time.Now())}
+
+		Ω(createDeployment()).Should(Succeed())
+		Ω(requests).Should(Equal(2))
+		Ω(output.String()).Should(ContainSubstring("rate limit exceeded, retrying in"))
+	})
+
+	It("does not wait for a rate limit reset beyond the budget", func() {
+		source.MaxRateLimitWait = &resource.Duration{Duration: time.Minute}
+		responses = []func(w http.ResponseWriter){rateLimited(time.Now().Add(time.Hour))}
+
+		Ω(getDeployment()).Should(MatchError(ContainSubstring("API rate limit exceeded")))
+		Ω(requests).Should(Equal(1))
+	})
+
+	It("honours Retry-After on secondary rate limits", func() {
+		responses = []func(w http.ResponseWriter){secondaryRateLimited}
+
+		Ω(createDeployment()).Should(Succeed())
+		Ω(requests).Should(Equal(2))
+		Ω(output.String()).Should(ContainSubstring("secondary rate limit exceeded, retrying in"))
+	})
+})
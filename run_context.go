@@ -0,0 +1,38 @@
+package resource
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// NewRunContext returns the context that a check, in or out run should use.
+// It is cancelled when the process receives SIGINT or SIGTERM, as Concourse
+// sends when a build is aborted, or once the source's timeout has passed.
+func NewRunContext(source Source) (context.Context, context.CancelFunc) {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if source.Timeout != nil {
+		ctx, cancel = context.WithTimeout(context.Background(), source.Timeout.Duration)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			Sayf("received %s, cancelling\n", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(signals)
+	}()
+
+	return ctx, cancel
+}
@@ -0,0 +1,145 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"unicode/utf8"
+)
+
+// GitHub rejects deployment and status descriptions longer than this.
+const maxDescriptionLength = 140
+
+// TemplateData is available to the templates in description, environment,
+// ref, environment_url, log_url, initial_description and initial_log_url.
+// The deployment fields are the values of the other params, after their own
+// templates have been rendered.
+type TemplateData struct {
+	BuildID        string
+	BuildName      string
+	BuildJobName   string
+	Pipeline       string
+	Team           string
+	ATCExternalURL string
+	BuildURL       string
+
+	ID          string
+	Ref         string
+	ShortRef    string
+	Environment string
+	Task        string
+	State       string
+}
+
+func newTemplateData(p *OutParams) TemplateData {
+	return TemplateData{
+		BuildID:        os.Getenv("BUILD_ID"),
+		BuildName:      os.Getenv("BUILD_NAME"),
+		BuildJobName:   os.Getenv("BUILD_JOB_NAME"),
+		Pipeline:       os.Getenv("BUILD_PIPELINE_NAME"),
+		Team:           os.Getenv("BUILD_TEAM_NAME"),
+		ATCExternalURL: os.Getenv("ATC_EXTERNAL_URL"),
+		BuildURL:       buildURL(),
+
+		ID:          stringValue(p.ID),
+		Ref:         stringValue(p.Ref),
+		ShortRef:    p.shortRef,
+		Environment: stringValue(p.Environment),
+		Task:        stringValue(p.Task),
+		State:       stringValue(p.State),
+	}
+}
+
+// buildURL is the URL of the Concourse build that is running the resource.
+func buildURL() string {
+	return fmt.Sprintf("%v/teams/%v/pipelines/%v/jobs/%v/builds/%v",
+		os.Getenv("ATC_EXTERNAL_URL"), os.Getenv("BUILD_TEAM_NAME"), os.Getenv("BUILD_PIPELINE_NAME"), os.Getenv("BUILD_JOB_NAME"), os.Getenv("BUILD_NAME"))
+}
+
+// renderTemplates renders the params that support templates. ref and
+// environment are rendered first, so that the others can use them. Only
+// strings written in the params are rendered. Values read from files and
+// environment variables are used as they are, as they may contain braces of
+// their own, and must not be able to read other files or variables.
+func (p *OutParams) renderTemplates(sourceDir string) error {
+	params := []struct {
+		name  string
+		raw   json.RawMessage
+		value **string
+	}{
+		{"ref", p.RawRef, &p.Ref},
+		{"environment", p.RawEnvironment, &p.Environment},
+		{"description", p.RawDescription, &p.Description},
+		{"environment_url", p.RawEnvironmentURL, &p.EnvironmentURL},
+		{"log_url", p.RawLogURL, &p.LogURL},
+		{"initial_description", p.RawInitialDescription, &p.InitialDescription},
+		{"initial_log_url", p.RawInitialLogURL, &p.InitialLogURL},
+	}
+
+	for _, param := range params {
+		if !isLiteral(param.raw) || *param.value == nil || !strings.Contains(**param.value, "{{") {
+			continue
+		}
+
+		rendered, err := renderTemplate(param.name, **param.value, sourceDir, newTemplateData(p))
+		if err != nil {
+			return &ReadParamError{Param: param.name, Err: err}
+		}
+		*param.value = &rendered
+	}
+
+	for _, description := range []**string{&p.Description, &p.InitialDescription} {
+		if *description != nil {
+			truncated := truncate(**description, maxDescriptionLength)
+			*description = &truncated
+		}
+	}
+
+	return nil
+}
+
+// isLiteral reports whether a raw param was given as a string, rather than
+// read from a file or environment variable.
+func isLiteral(raw json.RawMessage) bool {
+	raw = bytes.TrimSpace(raw)
+	return len(raw) > 0 && raw[0] == '"'
+}
+
+func renderTemplate(name, text, sourceDir string, data TemplateData) (string, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"file": func(path string) (string, error) {
+			return fileContents(sourceDir, path)
+		},
+		"env": os.Getenv,
+	}).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
+
+// truncate shortens s to at most max characters, ending it with an ellipsis
+// when anything was cut off.
+func truncate(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+
+	runes := []rune(s)
+	return string(runes[:max-1]) + "…"
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
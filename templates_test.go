@@ -0,0 +1,101 @@
+package resource_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Templates in params", func() {
+	var (
+		sourceDir string
+		p         resource.OutParams
+	)
+
+	BeforeEach(func() {
+		var err error
+		sourceDir, err = ioutil.TempDir("", "github-deployment")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		os.Setenv("BUILD_NAME", "42")
+		os.Setenv("BUILD_JOB_NAME", "deploy")
+		os.Setenv("BUILD_PIPELINE_NAME", "app")
+		os.Setenv("BUILD_TEAM_NAME", "main")
+		os.Setenv("ATC_EXTERNAL_URL", "https://ci.example.com")
+
+		p = resource.OutParams{}
+	})
+
+	AfterEach(func() {
+		Ω(os.RemoveAll(sourceDir)).Should(Succeed())
+		for _, name := range []string{"BUILD_NAME", "BUILD_JOB_NAME", "BUILD_PIPELINE_NAME", "BUILD_TEAM_NAME", "ATC_EXTERNAL_URL"} {
+			os.Unsetenv(name)
+		}
+	})
+
+	resolve := func(params string) error {
+		Ω(json.Unmarshal([]byte(params), &p)).Should(Succeed())
+		return p.Resolve(sourceDir)
+	}
+
+	It("renders build metadata", func() {
+		Ω(resolve(`{"description": "Deployed by build {{.BuildName}} of {{.Pipeline}}", "log_url": "{{.BuildURL}}"}`)).Should(Succeed())
+		Ω(*p.Description).Should(Equal("Deployed by build 42 of app"))
+		Ω(*p.LogURL).Should(Equal("https://ci.example.com/teams/main/pipelines/app/jobs/deploy/builds/42"))
+	})
+
+	It("renders file contents and environment variables", func() {
+		Ω(ioutil.WriteFile(filepath.Join(sourceDir, "version"), []byte("1.2.3\n"), 0644)).Should(Succeed())
+
+		Ω(resolve(`{"ref": "v{{file \"version\"}}", "environment": "{{env \"BUILD_TEAM_NAME\"}}-review"}`)).Should(Succeed())
+		Ω(*p.Ref).Should(Equal("v1.2.3"))
+		Ω(*p.Environment).Should(Equal("main-review"))
+	})
+
+	It("renders the other deployment fields", func() {
+		Ω(resolve(`{"id": "7", "environment": "pr-{{.BuildName}}", "environment_url": "https://{{.Environment}}.example.com", "description": "{{.State}} deployment {{.ID}}", "state": "success"}`)).Should(Succeed())
+		Ω(*p.Environment).Should(Equal("pr-42"))
+		Ω(*p.EnvironmentURL).Should(Equal("https://pr-42.example.com"))
+		Ω(*p.Description).Should(Equal("success deployment 7"))
+	})
+
+	It("truncates long descriptions with an ellipsis", func() {
+		Ω(resolve(`{"description": "{{.Pipeline}}` + strings.Repeat("x", 200) + `"}`)).Should(Succeed())
+		Ω([]rune(*p.Description)).Should(HaveLen(140))
+		Ω(*p.Description).Should(HaveSuffix("x…"))
+	})
+
+	It("names the param when a template is invalid", func() {
+		Ω(resolve(`{"description": "{{.Nope}}"}`)).Should(MatchError(ContainSubstring("reading description: ")))
+		Ω(resolve(`{"ref": "{{"}`)).Should(MatchError(ContainSubstring("reading ref: ")))
+	})
+
+	It("does not render values read from files or environment variables", func() {
+		Ω(ioutil.WriteFile(filepath.Join(sourceDir, "message"), []byte("Set {{ .Values.image }} from {{env \"SECRET\"}}\n"), 0644)).Should(Succeed())
+		os.Setenv("DEPLOY_URL", "https://{{.Nope}}.example.com")
+		defer os.Unsetenv("DEPLOY_URL")
+
+		Ω(resolve(`{"description": {"file": "message"}, "environment_url": {"env": "DEPLOY_URL"}}`)).Should(Succeed())
+		Ω(*p.Description).Should(Equal(`Set {{ .Values.image }} from {{env "SECRET"}}`))
+		Ω(*p.EnvironmentURL).Should(Equal("https://{{.Nope}}.example.com"))
+	})
+
+	It("does not render file contents included by a template", func() {
+		Ω(ioutil.WriteFile(filepath.Join(sourceDir, "message"), []byte(`{{env "BUILD_TEAM_NAME"}}`), 0644)).Should(Succeed())
+
+		Ω(resolve(`{"description": "Message: {{file \"message\"}}"}`)).Should(Succeed())
+		Ω(*p.Description).Should(Equal(`Message: {{env "BUILD_TEAM_NAME"}}`))
+	})
+
+	It("leaves other params alone", func() {
+		Ω(resolve(`{"task": "{{.BuildName}}"}`)).Should(Succeed())
+		Ω(*p.Task).Should(Equal("{{.BuildName}}"))
+	})
+})
@@ -0,0 +1,64 @@
+package resource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/mitchellh/colorstring"
+)
+
+// baseTransport builds the transport that every GitHub request goes through,
+// configured for GitHub Enterprise instances behind an internal CA, mutual
+// TLS or a proxy.
+func baseTransport(source Source, writer io.Writer) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	tlsConfig := &tls.Config{}
+
+	if source.CACert != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM([]byte(source.CACert)) {
+			return nil, errors.New("ca_cert does not contain any PEM encoded certificates")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if source.ClientCert != "" || source.ClientKey != "" {
+		if source.ClientCert == "" || source.ClientKey == "" {
+			return nil, errors.New("client_cert and client_key must be set together")
+		}
+
+		cert, err := tls.X509KeyPair([]byte(source.ClientCert), []byte(source.ClientKey))
+		if err != nil {
+			return nil, fmt.Errorf("loading client_cert and client_key: %s", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if source.InsecureSkipVerify {
+		fmt.Fprint(writer, colorstring.Color(
+			"[yellow]WARNING: insecure_skip_verify is set, so the GitHub server's TLS certificate will not be verified. "+
+				"Anyone able to intercept the connection can read your credentials.\n"))
+		tlsConfig.InsecureSkipVerify = true
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	if source.HTTPProxy != "" {
+		proxyURL, err := url.Parse(source.HTTPProxy)
+		if err != nil {
+			return nil, fmt.Errorf("parsing http_proxy: %s", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	return transport, nil
+}
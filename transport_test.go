@@ -0,0 +1,173 @@
+package resource_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/google/go-github/v28/github"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+// selfSignedCert returns a PEM encoded certificate and key for a self signed
+// client certificate.
+func selfSignedCert() (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "concourse"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	Ω(err).ShouldNot(HaveOccurred())
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	return string(certPEM), string(keyPEM)
+}
+
+var _ = Describe("Transport configuration", func() {
+	var (
+		server *httptest.Server
+		source resource.Source
+		output *bytes.Buffer
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1}`)
+	})
+
+	BeforeEach(func() {
+		output = &bytes.Buffer{}
+		source = resource.Source{
+			User:       "owner",
+			Repository: "repo",
+			MaxRetries: github.Int(0),
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	getDeployment := func() error {
+		client, err := resource.NewGitHubClient(context.Background(), source, output)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.GetDeployment(context.Background(), 1)
+		return err
+	}
+
+	Context("when GitHub uses a certificate from an internal CA", func() {
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(handler)
+			source.GitHubAPIURL = server.URL + "/"
+		})
+
+		It("fails without the CA", func() {
+			Ω(getDeployment()).Should(MatchError(ContainSubstring("certificate")))
+		})
+
+		It("trusts the configured ca_cert", func() {
+			source.CACert = string(pem.EncodeToMemory(&pem.Block{
+				Type:  "CERTIFICATE",
+				Bytes: server.Certificate().Raw,
+			}))
+
+			Ω(getDeployment()).Should(Succeed())
+		})
+
+		It("rejects a ca_cert without any certificates", func() {
+			source.CACert = "not a certificate"
+
+			Ω(getDeployment()).Should(MatchError("ca_cert does not contain any PEM encoded certificates"))
+		})
+
+		It("skips verification with a loud warning when asked to", func() {
+			source.InsecureSkipVerify = true
+
+			Ω(getDeployment()).Should(Succeed())
+			Ω(output.String()).Should(ContainSubstring("WARNING: insecure_skip_verify is set"))
+		})
+	})
+
+	Context("when GitHub requires a client certificate", func() {
+		var clientCert, clientKey string
+
+		BeforeEach(func() {
+			clientCert, clientKey = selfSignedCert()
+
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM([]byte(clientCert))
+
+			server = httptest.NewUnstartedServer(handler)
+			server.TLS = &tls.Config{
+				ClientAuth: tls.RequireAndVerifyClientCert,
+				ClientCAs:  pool,
+			}
+			server.StartTLS()
+
+			source.GitHubAPIURL = server.URL + "/"
+			source.InsecureSkipVerify = true
+		})
+
+		It("fails without a client certificate", func() {
+			Ω(getDeployment()).ShouldNot(Succeed())
+		})
+
+		It("presents the configured client certificate", func() {
+			source.ClientCert = clientCert
+			source.ClientKey = clientKey
+
+			Ω(getDeployment()).Should(Succeed())
+		})
+
+		It("requires both the certificate and the key", func() {
+			source.ClientCert = clientCert
+
+			Ω(getDeployment()).Should(MatchError("client_cert and client_key must be set together"))
+		})
+	})
+
+	Context("when a proxy is configured", func() {
+		var proxiedURLs []string
+
+		BeforeEach(func() {
+			proxiedURLs = []string{}
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				proxiedURLs = append(proxiedURLs, r.URL.String())
+				fmt.Fprint(w, `{"id":1}`)
+			}))
+
+			source.GitHubAPIURL = "http://github.example.com/api/v3/"
+			source.HTTPProxy = server.URL
+		})
+
+		It("sends requests through the proxy", func() {
+			Ω(getDeployment()).Should(Succeed())
+			Ω(proxiedURLs).Should(Equal([]string{"http://github.example.com/api/v3/repos/owner/repo/deployments/1"}))
+		})
+	})
+})
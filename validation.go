@@ -0,0 +1,248 @@
+package resource
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// outTypeParams lists the params that apply to each out type, other than
+// type itself.
+var outTypeParams = map[string][]string{
+	"status": {
+		"id", "state", "description", "environment", "environment_url", "log_url",
+		"target_url", "auto_inactive",
+	},
+	"deployment": {
+		"ref", "ref_from_repo", "allow_dirty", "environment", "task", "description", "auto_merge", "payload", "payload_path",
+		"required_contexts", "transient_environment", "production_environment",
+		"initial_status", "initial_description", "initial_log_url",
+	},
+	"delete": {
+		"id",
+	},
+	"cleanup": {
+		"keep_last", "older_than", "environment", "transient_only", "delete", "dry_run",
+	},
+	"environment": {
+		"environment", "wait_timer", "reviewers", "deployment_branches", "delete",
+	},
+}
+
+// ParamsError lists every problem found when validating out params.
+type ParamsError []string
+
+func (e ParamsError) Error() string {
+	return "invalid params:\n  " + strings.Join(e, "\n  ")
+}
+
+// ResolveAndValidate resolves the params and then validates them, reporting
+// every problem at once. The params that were set are checked before they
+// are resolved, so that a param that cannot be read does not hide mistakes
+// in the others. Values are only checked when every param could be read.
+func (p *OutParams) ResolveAndValidate(sourceDir string) error {
+	problems := p.validateKeys()
+
+	if err := p.Resolve(sourceDir); err != nil {
+		problems = append(problems, err.Error())
+	} else {
+		problems = append(problems, p.validateValues()...)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// Validate checks the out params without making any API calls, and reports
+// every problem it finds at once. Unknown params are only found when the
+// params have been unmarshalled from JSON.
+func (p OutParams) Validate() error {
+	problems := append(p.validateKeys(), p.validateValues()...)
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func (p OutParams) outType() string {
+	if p.Type != nil {
+		return *p.Type
+	}
+	return "status"
+}
+
+// validateKeys checks the type and the names of the params that were set,
+// which do not depend on reading any of their values.
+func (p OutParams) validateKeys() ParamsError {
+	problems := ParamsError{}
+
+	outType := p.outType()
+	allowed, ok := outTypeParams[outType]
+	if !ok {
+		problems = append(problems, unknownValue("type", outType, outTypes()))
+	}
+
+	for _, key := range p.keys {
+		if key == "type" {
+			continue
+		}
+
+		switch {
+		case !knownParam(key):
+			problem := fmt.Sprintf("unknown param %q", key)
+			if suggestion := closest(key, knownParams()); suggestion != "" {
+				problem += fmt.Sprintf(", did you mean %q?", suggestion)
+			}
+			problems = append(problems, problem)
+		case ok && !contains(allowed, key):
+			problems = append(problems, fmt.Sprintf("param %q does not apply to type %s", key, outType))
+		}
+	}
+
+	return problems
+}
+
+// validateValues checks that the params each type requires are set, and
+// that the resolved values are valid.
+func (p OutParams) validateValues() ParamsError {
+	problems := ParamsError{}
+
+	switch p.outType() {
+	case "status":
+		if p.ID == nil {
+			problems = append(problems, "id is a required parameter")
+		}
+		if p.State == nil {
+			problems = append(problems, "state is a required parameter")
+		} else if !validStatusState(*p.State) {
+			problems = append(problems, unknownValue("state", *p.State, StatusStates))
+		}
+	case "deployment":
+		if p.Ref == nil {
+			problems = append(problems, "ref is a required parameter")
+		}
+		if p.RefFromRepo != nil && contains(p.keys, "ref") {
+			problems = append(problems, "only one of ref or ref_from_repo can be set")
+		}
+		if p.AllowDirty && p.RefFromRepo == nil {
+			problems = append(problems, "allow_dirty requires ref_from_repo")
+		}
+		if p.InitialStatus != nil && !validStatusState(*p.InitialStatus) {
+			problems = append(problems, unknownValue("initial_status", *p.InitialStatus, StatusStates))
+		}
+		if p.InitialStatus == nil && (p.InitialDescription != nil || p.InitialLogURL != nil) {
+			problems = append(problems, "initial_description and initial_log_url require initial_status")
+		}
+	case "delete":
+		if p.ID == nil {
+			problems = append(problems, "id is a required parameter")
+		}
+	case "cleanup":
+		if p.KeepLast == nil && p.OlderThan == nil {
+			problems = append(problems, "one of keep_last or older_than is required")
+		}
+	case "environment":
+		if p.Environment == nil || *p.Environment == "" {
+			problems = append(problems, "environment is a required parameter")
+		}
+	}
+
+	return problems
+}
+
+func unknownValue(name, value string, valid []string) string {
+	problem := fmt.Sprintf("unknown %s %q", name, value)
+	if suggestion := closest(value, valid); suggestion != "" {
+		problem += fmt.Sprintf(", did you mean %q?", suggestion)
+	}
+	return problem + fmt.Sprintf(" (must be one of %s)", strings.Join(valid, ", "))
+}
+
+func outTypes() []string {
+	types := []string{}
+	for outType := range outTypeParams {
+		types = append(types, outType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+func knownParams() []string {
+	seen := map[string]bool{}
+	params := []string{"type"}
+	for _, outType := range outTypes() {
+		for _, param := range outTypeParams[outType] {
+			if !seen[param] {
+				seen[param] = true
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
+
+func knownParam(key string) bool {
+	return contains(knownParams(), key)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// closest returns the candidate nearest to value by edit distance, as long as
+// it is near enough to be a likely typo, and an empty string otherwise.
+func closest(value string, candidates []string) string {
+	best := ""
+	bestDistance := len(value)/3 + 1
+	for _, candidate := range candidates {
+		if d := editDistance(value, candidate); d < bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent characters needed to turn a into b.
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return d[len(a)][len(b)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
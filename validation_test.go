@@ -0,0 +1,112 @@
+package resource_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	resource "github.com/ahume/github-deployment-resource"
+)
+
+var _ = Describe("Validating out params", func() {
+	validate := func(params string) error {
+		var p resource.OutParams
+		Ω(json.Unmarshal([]byte(params), &p)).Should(Succeed())
+		Ω(p.Resolve("")).Should(Succeed())
+		return p.Validate()
+	}
+
+	problems := func(err error) []string {
+		Ω(err).Should(HaveOccurred())
+		Ω(err).Should(BeAssignableToTypeOf(resource.ParamsError{}))
+		return err.(resource.ParamsError)
+	}
+
+	It("accepts valid params for each type", func() {
+		Ω(validate(`{"id": "1", "state": "queued", "log_url": "https://ci.example.com"}`)).Should(Succeed())
+		Ω(validate(`{"type": "deployment", "ref": "main", "auto_merge": false}`)).Should(Succeed())
+		Ω(validate(`{"type": "delete", "id": "1"}`)).Should(Succeed())
+		Ω(validate(`{"type": "cleanup", "keep_last": 3, "dry_run": true}`)).Should(Succeed())
+		Ω(validate(`{"type": "environment", "environment": "pr-1", "wait_timer": 5}`)).Should(Succeed())
+	})
+
+	It("rejects unknown types and suggests the closest", func() {
+		Ω(problems(validate(`{"type": "deploymnt", "ref": "main"}`))).Should(Equal([]string{
+			`unknown type "deploymnt", did you mean "deployment"? (must be one of cleanup, delete, deployment, environment, status)`,
+		}))
+	})
+
+	It("rejects unknown states and suggests the closest", func() {
+		Ω(problems(validate(`{"id": "1", "state": "sucess"}`))).Should(Equal([]string{
+			`unknown state "sucess", did you mean "success"? (must be one of error, failure, inactive, in_progress, queued, pending, success)`,
+		}))
+	})
+
+	It("suggests the closest param for typos", func() {
+		Ω(problems(validate(`{"id": "1", "state": "success", "descripton": "hi"}`))).Should(Equal([]string{
+			`unknown param "descripton", did you mean "description"?`,
+		}))
+	})
+
+	It("does not suggest params that are not close", func() {
+		Ω(problems(validate(`{"id": "1", "state": "success", "colour": "red"}`))).Should(Equal([]string{
+			`unknown param "colour"`,
+		}))
+	})
+
+	It("reports params that do not apply to the type", func() {
+		Ω(problems(validate(`{"type": "delete", "id": "1", "state": "success"}`))).Should(Equal([]string{
+			`param "state" does not apply to type delete`,
+		}))
+	})
+
+	It("checks the initial status of a deployment", func() {
+		Ω(validate(`{"type": "deployment", "ref": "main", "initial_status": "pending", "initial_description": "hi"}`)).Should(Succeed())
+		Ω(problems(validate(`{"type": "deployment", "ref": "main", "initial_status": "pendng"}`))).Should(Equal([]string{
+			`unknown initial_status "pendng", did you mean "pending"? (must be one of error, failure, inactive, in_progress, queued, pending, success)`,
+		}))
+		Ω(problems(validate(`{"type": "deployment", "ref": "main", "initial_log_url": "https://ci.example.com"}`))).Should(Equal([]string{
+			"initial_description and initial_log_url require initial_status",
+		}))
+	})
+
+	It("reports every problem at once", func() {
+		err := validate(`{"type": "deployment", "state": "sucess", "taks": "deploy"}`)
+		Ω(problems(err)).Should(Equal([]string{
+			`param "state" does not apply to type deployment`,
+			`unknown param "taks", did you mean "task"?`,
+			`ref is a required parameter`,
+		}))
+		Ω(err.Error()).Should(Equal("invalid params:\n" +
+			`  param "state" does not apply to type deployment` + "\n" +
+			`  unknown param "taks", did you mean "task"?` + "\n" +
+			`  ref is a required parameter`))
+	})
+
+	Describe("resolving and validating together", func() {
+		resolveAndValidate := func(params string) error {
+			var p resource.OutParams
+			Ω(json.Unmarshal([]byte(params), &p)).Should(Succeed())
+			return p.ResolveAndValidate("")
+		}
+
+		It("reports params that cannot be read along with the other problems", func() {
+			Ω(problems(resolveAndValidate(`{"type": "deploymnt", "ref": {"file": "missing"}}`))).Should(Equal([]string{
+				`unknown type "deploymnt", did you mean "deployment"? (must be one of cleanup, delete, deployment, environment, status)`,
+				"reading ref from missing: open missing: no such file or directory",
+			}))
+		})
+
+		It("checks values once every param could be read", func() {
+			Ω(problems(resolveAndValidate(`{"id": "1", "state": "sucess", "colour": "red"}`))).Should(Equal([]string{
+				`unknown param "colour"`,
+				`unknown state "sucess", did you mean "success"? (must be one of error, failure, inactive, in_progress, queued, pending, success)`,
+			}))
+		})
+
+		It("accepts valid params", func() {
+			Ω(resolveAndValidate(`{"id": "1", "state": "success"}`)).Should(Succeed())
+		})
+	})
+})